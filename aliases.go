@@ -0,0 +1,172 @@
+package typeconv
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// aliasNode renames the keys of map sources, such as decoded map[string]any
+// documents, before the fallback codec decodes them into its destination
+// type, so that field aliases apply to map sources as they do to structs.
+// It mirrors the shape of the destination type down to the structs whose
+// fields have aliases.
+type aliasNode struct {
+	// fields are the struct fields to look up in a map source.
+	fields []aliasField
+	// elem is the node of slice and array elements and map values.
+	elem *aliasNode
+}
+
+type aliasField struct {
+	// key is the key the codec decodes into the field.
+	key string
+	// names are the lower-cased keys feeding the field, by precedence: the
+	// key itself, the field's name and its aliases in declaration order.
+	// Empty for fields only listed for nested aliases.
+	names []string
+	// nested renames the keys of the field's value.
+	nested *aliasNode
+}
+
+// aliasesFor returns the alias node for dt, whose struct fields are named
+// by tag, or nil if no field below dt has aliases.
+func aliasesFor(dt reflect.Type, tag string) *aliasNode {
+	return aliasBuilder{tag: tag, seen: map[reflect.Type]*aliasNode{}}.node(dt)
+}
+
+type aliasBuilder struct {
+	tag string
+	// seen terminates recursion through self-referencing types.
+	seen map[reflect.Type]*aliasNode
+}
+
+func (b aliasBuilder) node(t reflect.Type) *aliasNode {
+	t = deref(t)
+	if n, ok := b.seen[t]; ok {
+		return n
+	}
+	// Self-references resolve to n while it is built; if it ends up empty
+	// they keep an empty node, which renames nothing.
+	n := &aliasNode{}
+	b.seen[t] = n
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n.elem = b.node(t.Elem())
+	case reflect.Struct:
+		n.fields = b.fields(t)
+	}
+	if n.elem == nil && n.fields == nil {
+		n = nil
+	}
+	b.seen[t] = n
+	return n
+}
+
+func (b aliasBuilder) fields(t reflect.Type) []aliasField {
+	fm := getFields(t, b.tag)
+	var fields []aliasField
+	for name, idx := range fm.index {
+		ft := fm.tags[name]
+		if ft.dir == dirRead {
+			continue
+		}
+		f := t.FieldByIndex(idx)
+		af := aliasField{key: jsonKey(f), nested: b.node(f.Type)}
+		if len(ft.aliases) > 0 {
+			for _, n := range append([]string{strings.ToLower(af.key), name}, ft.aliases...) {
+				if !slices.Contains(af.names, n) {
+					af.names = append(af.names, n)
+				}
+			}
+		}
+		if af.names != nil || af.nested != nil {
+			fields = append(fields, af)
+		}
+	}
+	// Map iteration order must not decide which field claims a key.
+	slices.SortFunc(fields, func(a, b aliasField) int { return strings.Compare(a.key, b.key) })
+	return fields
+}
+
+// jsonKey returns the key encoding/json decodes into f.
+func jsonKey(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+// apply returns v with the keys of its maps renamed, unwrapped from any
+// interface. Maps and slices are copied where needed; v itself is never
+// modified.
+func (n *aliasNode) apply(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String && n.fields != nil:
+		return n.rename(v)
+	case v.Kind() == reflect.Map && n.elem != nil:
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), n.elem.apply(iter.Value()))
+		}
+		return out
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && n.elem != nil:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		if v.Kind() == reflect.Slice {
+			out.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		}
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(n.elem.apply(v.Index(i)))
+		}
+		return out
+	}
+	return v
+}
+
+// rename copies the string-keyed map v, moving the value of the first alias
+// present to the key of a field whose own key is missing.
+func (n *aliasNode) rename(v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return v
+	}
+	keys := make(map[string]reflect.Value, v.Len())
+	out := reflect.MakeMapWithSize(v.Type(), v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		if lk := strings.ToLower(k.String()); !keys[lk].IsValid() {
+			keys[lk] = k
+		}
+		out.SetMapIndex(k, iter.Value())
+	}
+	for _, f := range n.fields {
+		key, ok := keys[strings.ToLower(f.key)]
+		if len(f.names) > 0 {
+			for _, name := range f.names {
+				if key, ok = keys[name]; ok {
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		val := v.MapIndex(key)
+		if f.nested != nil {
+			val = f.nested.apply(val)
+		}
+		if key.String() != f.key {
+			out.SetMapIndex(key, reflect.Value{})
+			key = reflect.ValueOf(f.key).Convert(v.Type().Key())
+		}
+		out.SetMapIndex(key, val)
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package typeconv
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Default cache limits. Plans and field maps are small, but schema-driven
+// callers creating types with reflect.StructOf would otherwise grow the
+// caches without bound.
+const (
+	DefaultPlanCacheLimit  = 4096
+	DefaultFieldCacheLimit = 4096
+)
+
+// CacheCounters reports the size and activity of one cache. Misses count
+// every lookup that found no entry; Builds count the entries actually built,
+// which is fewer when concurrent misses of the same key share one build.
+type CacheCounters struct {
+	Size      int
+	Limit     int
+	Hits      uint64
+	Misses    uint64
+	Builds    uint64
+	Evictions uint64
+}
+
+// CacheStats reports the package's caches: the plans of the default
+// Converter, and the struct field maps and parsed default tags shared by
+// all Converters.
+type CacheStats struct {
+	Plans     CacheCounters
+	FieldMaps CacheCounters
+	Defaults  CacheCounters
+}
+
+// Stats returns a snapshot of the cache counters.
+func Stats() CacheStats {
+	return CacheStats{
+		Plans:     defaultConverter.plans.stats(),
+		FieldMaps: fieldMapCache.stats(),
+		Defaults:  defaultsCache.stats(),
+	}
+}
+
+// SetCacheLimits bounds the number of plans cached by the default Converter
+// and of cached field maps and default tags; the least recently used entries
+// are evicted beyond it. A limit of 0 or less means unbounded.
+func SetCacheLimits(plans, fields int) {
+	defaultConverter.plans.setLimit(plans)
+	fieldMapCache.setLimit(fields)
+	defaultsCache.setLimit(fields)
+}
+
+// ResetCaches drops every entry of the caches reported by Stats and zeroes
+// their counters. Plans already returned by BuildPlan stay valid.
+func ResetCaches() {
+	defaultConverter.ResetCache()
+	fieldMapCache.reset()
+	defaultsCache.reset()
+}
+
+// lruCache is a size-bounded cache that builds missing entries once, however
+// many goroutines ask for them concurrently. Hits read a sync.Map without
+// locking and only mark the entry as used; eviction approximates LRU by
+// giving used entries a second chance (the CLOCK algorithm).
+type lruCache[K comparable, V any] struct {
+	entries sync.Map // of K to *lruEntry[K, V]
+	hits    atomic.Uint64
+
+	mu       sync.Mutex
+	limit    int
+	order    *list.List // of *lruEntry[K, V], most recently added first
+	inflight map[K]*lruCall[V]
+	counters CacheCounters // Hits are counted in hits
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+	el    *list.Element
+	// used is set by hits and cleared by eviction passes.
+	used atomic.Bool
+}
+
+// lruCall is a build in progress; waiters block on done.
+type lruCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func newLRUCache[K comparable, V any](limit int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		limit:    limit,
+		order:    list.New(),
+		inflight: make(map[K]*lruCall[V]),
+	}
+}
+
+// lookup returns the cached value for key, counting only hits, so that a
+// miss can go on to get without building its closure up front.
+func (c *lruCache[K, V]) lookup(key K) (V, bool) {
+	v, ok := c.entries.Load(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	e := v.(*lruEntry[K, V])
+	// Only write the shared flag when it changes.
+	if !e.used.Load() {
+		e.used.Store(true)
+	}
+	c.hits.Add(1)
+	return e.value, true
+}
+
+// get returns the cached value for key, building and caching it if missing.
+// Errors are returned to every caller sharing the build and not cached.
+func (c *lruCache[K, V]) get(key K, build func() (V, error)) (V, error) {
+	if v, ok := c.lookup(key); ok {
+		return v, nil
+	}
+	c.mu.Lock()
+	if v, ok := c.entries.Load(key); ok {
+		// Added since the lookup.
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return v.(*lruEntry[K, V]).value, nil
+	}
+	c.counters.Misses++
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &lruCall[V]{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.counters.Builds++
+	c.mu.Unlock()
+
+	completed := false
+	defer func() {
+		if !completed {
+			// build panicked; the panic continues in this goroutine only.
+			call.err = errBuildPanicked
+		}
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if call.err == nil {
+			c.add(key, call.value)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = build()
+	completed = true
+	return call.value, call.err
+}
+
+var errBuildPanicked = errors.New("typeconv: concurrent cache build panicked")
+
+// add inserts key, evicting beyond the limit. c.mu must be held.
+func (c *lruCache[K, V]) add(key K, value V) {
+	if v, ok := c.entries.Load(key); ok {
+		c.order.Remove(v.(*lruEntry[K, V]).el)
+	}
+	e := &lruEntry[K, V]{key: key, value: value}
+	e.el = c.order.PushFront(e)
+	c.entries.Store(key, e)
+	c.evict()
+}
+
+// evict drops entries beyond the limit, oldest first, moving entries used
+// since the last pass to the front instead, at most once each per call.
+// c.mu must be held.
+func (c *lruCache[K, V]) evict() {
+	chances := c.order.Len()
+	for c.limit > 0 && c.order.Len() > c.limit {
+		el := c.order.Back()
+		e := el.Value.(*lruEntry[K, V])
+		if chances > 0 && e.used.Swap(false) {
+			chances--
+			c.order.MoveToFront(el)
+			continue
+		}
+		c.order.Remove(el)
+		c.entries.CompareAndDelete(e.key, e)
+		c.counters.Evictions++
+	}
+}
+
+func (c *lruCache[K, V]) setLimit(limit int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limit = limit
+	c.evict()
+}
+
+func (c *lruCache[K, V]) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries.Clear()
+	c.counters = CacheCounters{}
+	c.hits.Store(0)
+}
+
+func (c *lruCache[K, V]) stats() CacheCounters {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.counters
+	s.Hits = c.hits.Load()
+	s.Size, s.Limit = c.order.Len(), c.limit
+	return s
+}
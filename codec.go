@@ -0,0 +1,106 @@
+package typeconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// Codec is the fallback plans use for pairs no other rule converts: the
+// source is marshaled and the result unmarshaled into the destination.
+// Codecs are part of the plan cache key, so they should be comparable
+// values; plans with non-comparable codecs are not cached.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the default Codec, based on encoding/json. Plans encode into
+// a pooled buffer rather than a fresh slice; otherwise encoding and decoding
+// allocate as encoding/json does. The decoder options behave as the
+// json.Decoder methods of the same name; with DisallowUnknownFields, plans
+// reject struct sources with fields their struct destinations lack.
+type JSONCodec struct {
+	UseNumber             bool
+	DisallowUnknownFields bool
+}
+
+const maxPooledBuf = 64 << 10
+
+var jsonBufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+func (c JSONCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (c JSONCodec) Unmarshal(data []byte, v any) error {
+	if !c.UseNumber && !c.DisallowUnknownFields {
+		return json.Unmarshal(data, v)
+	}
+	return c.decode(json.NewDecoder(bytes.NewReader(data)), v)
+}
+
+func (c JSONCodec) decode(dec *json.Decoder, v any) error {
+	if c.UseNumber {
+		dec.UseNumber()
+	}
+	if c.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// transcode converts src into dst through a pooled buffer.
+func (c JSONCodec) transcode(dst, src any) error {
+	buf := jsonBufPool.Get().(*bytes.Buffer)
+	defer func() {
+		// Don't let one huge value pin a huge buffer.
+		if buf.Cap() <= maxPooledBuf {
+			buf.Reset()
+			jsonBufPool.Put(buf)
+		}
+	}()
+	if err := json.NewEncoder(buf).Encode(src); err != nil {
+		return err
+	}
+	if !c.UseNumber && !c.DisallowUnknownFields {
+		return json.Unmarshal(buf.Bytes(), dst)
+	}
+	return c.decode(json.NewDecoder(buf), dst)
+}
+
+// codecKey returns c as a plan cache key component, or false if c is not
+// comparable. The dynamic value is checked, so that a comparable struct
+// holding a slice in an interface field does not panic as a map key.
+func codecKey(c Codec) (Codec, bool) {
+	if _, ok := c.(JSONCodec); ok {
+		// The default; checking the value would allocate on every lookup.
+		return c, true
+	}
+	if !reflect.ValueOf(c).Comparable() {
+		return nil, false
+	}
+	return c, true
+}
+
+// zeroRoundTrips reports whether converting the zero st through codec may
+// be skipped in favour of the zero dt. It may not for types whose zero value
+// has a meaningful encoding or decoding, such as a MarshalJSON emitting a
+// sentinel or an UnmarshalJSON filling defaults, or for shapes like a zero
+// struct decoding into a map. Zero values the codec cannot round-trip at
+// all keep converting to zero.
+func zeroRoundTrips(codec Codec, st, dt reflect.Type) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = true
+		}
+	}()
+	data, err := codec.Marshal(reflect.Zero(st).Interface())
+	if err != nil {
+		return true
+	}
+	out := reflect.New(dt)
+	if err := codec.Unmarshal(data, out.Interface()); err != nil {
+		return true
+	}
+	return out.Elem().IsZero()
+}
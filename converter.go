@@ -0,0 +1,87 @@
+package typeconv
+
+import (
+	"context"
+	"slices"
+)
+
+// Converter holds options, extensions (converters, factories, hooks and
+// field extensions) and a cache of the plans built from them, so that
+// libraries in one binary, or parallel tests, can convert with different
+// settings without sharing state. Use it with ConvertWith, ConvertContextWith
+// and BuildPlanWith. Struct field maps and default tags depend only on the
+// types and are shared by all Converters.
+//
+// The package-level Convert, ConvertContext and BuildPlan use the default
+// Converter. A Converter is safe for concurrent use.
+type Converter struct {
+	opts       Options
+	extensions []any
+	// extensionKey is the extensionKey of extensions, which every plan key
+	// of c starts with.
+	extensionKey string
+	plans        *planCache
+	// seen admits plans of per-call extensions to plans.
+	seen seenKeys
+}
+
+var defaultConverter = &Converter{
+	opts:  defaultOptions.withDefaults(),
+	plans: newLRUCache[pair, any](DefaultPlanCacheLimit),
+}
+
+// DefaultConverter returns the Converter used by the package-level
+// functions. It has the default options and no extensions.
+func DefaultConverter() *Converter { return defaultConverter }
+
+// New returns a Converter with the given options and extensions, which
+// apply to every conversion it performs. Extensions accepts everything
+// Convert accepts as custom converters; invalid ones are reported here.
+func New(opts Options, extensions ...any) (*Converter, error) {
+	if _, err := buildLocalRegistry(extensions); err != nil {
+		return nil, err
+	}
+	extensions = slices.Clone(extensions)
+	return &Converter{
+		opts:         opts.withDefaults(),
+		extensions:   extensions,
+		extensionKey: extensionKey(extensions),
+		plans:        newLRUCache[pair, any](DefaultPlanCacheLimit),
+	}, nil
+}
+
+// Options returns the options of c, with defaults filled in.
+func (c *Converter) Options() Options { return c.opts }
+
+// Stats returns the counters of the plan cache of c.
+func (c *Converter) Stats() CacheCounters { return c.plans.stats() }
+
+// SetCacheLimit bounds the number of plans c caches; 0 or less means
+// unbounded.
+func (c *Converter) SetCacheLimit(plans int) { c.plans.setLimit(plans) }
+
+// ResetCache drops the plans cached by c and zeroes its counters.
+func (c *Converter) ResetCache() {
+	c.plans.reset()
+	c.seen.reset()
+}
+
+// BuildPlanWith returns the plan between S and D for c. Extensions are
+// added to those of c; plans are cached in c as BuildPlan caches them.
+func BuildPlanWith[S any, D any](c *Converter, extensions ...any) (*Plan[S, D], error) {
+	return buildPlan[S, D](c, c.opts, extensions)
+}
+
+// ConvertWith is Convert using c.
+func ConvertWith[S any, D any](c *Converter, src *S, dst *D, extensions ...any) error {
+	return ConvertContextWith(context.Background(), c, src, dst, extensions...)
+}
+
+// ConvertContextWith is ConvertContext using c.
+func ConvertContextWith[S any, D any](ctx context.Context, c *Converter, src *S, dst *D, extensions ...any) error {
+	p, err := BuildPlanWith[S, D](c, extensions...)
+	if err != nil {
+		return err
+	}
+	return p.convert(ctx, dst, src)
+}
@@ -0,0 +1,253 @@
+package typeconv
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// fieldDefault is a parsed default value for a destination field. value
+// returns a fresh copy on every call for types that hold references.
+type fieldDefault struct {
+	dstIndex []int
+	name     string
+	value    func() reflect.Value
+}
+
+// defaultExt marks a plan-level default. See Default.
+type defaultExt struct {
+	path  string
+	value any
+}
+
+// Default sets the default for the destination field at path, overriding any
+// default tag on the field. value is either a string, parsed like a default
+// tag, or a value assignable to the field's type. path follows the rules of
+// Transform and may name a field with no source counterpart.
+func Default(path string, value any) any {
+	return defaultExt{path: path, value: value}
+}
+
+func (r *localConverterRegistry) addDefault(e defaultExt) error {
+	cfg := r.fieldFor(e.path)
+	if cfg.def != nil {
+		return fmt.Errorf("duplicate default for %q", e.path)
+	}
+	cfg.def = &e
+	return nil
+}
+
+// resolve parses the plan-level default against the field type t.
+func (e *defaultExt) resolve(t reflect.Type) (func() reflect.Value, error) {
+	if text, ok := e.value.(string); ok && t.Kind() != reflect.Interface {
+		fn, err := parseDefault(text, t)
+		if err != nil {
+			return nil, fmt.Errorf("default for %q: %w", e.path, err)
+		}
+		return fn, nil
+	}
+	v := reflect.ValueOf(e.value)
+	if !v.IsValid() || !v.Type().AssignableTo(t) {
+		return nil, fmt.Errorf("default for %q must be a string or %s, got %T", e.path, t, e.value)
+	}
+	c := reflect.New(t).Elem()
+	c.Set(v)
+	if pointerFree(t) || t.Kind() == reflect.String {
+		return func() reflect.Value { return c }, nil
+	}
+	// Copy on every call so that conversions never share pointers, slices
+	// or maps, as with parsed defaults.
+	return func() reflect.Value { return deepCopy(c) }, nil
+}
+
+// deepCopy returns a copy of v sharing no pointers, slices or maps with it.
+// Unexported struct fields are copied shallowly.
+func deepCopy(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			out.Set(reflect.New(v.Type().Elem()))
+			out.Elem().Set(deepCopy(v.Elem()))
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			out.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				out.Index(i).Set(deepCopy(v.Index(i)))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(deepCopy(v.Index(i)))
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			out.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			iter := v.MapRange()
+			for iter.Next() {
+				out.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+			}
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			out.Set(deepCopy(v.Elem()))
+		}
+	case reflect.Struct:
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+	default:
+		out.Set(v)
+	}
+	return out
+}
+
+// parseDefault parses text into a value of type t. Booleans, numbers,
+// strings and time.Duration use their usual text forms, types implementing
+// encoding.TextUnmarshaler parse themselves, pointers parse their element
+// and anything else is decoded as JSON.
+func parseDefault(text string, t reflect.Type) (func() reflect.Value, error) {
+	v, err := parseText(text, t)
+	if err != nil {
+		return nil, err
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return func() reflect.Value { return v }, nil
+	}
+	// Re-parse so that conversions never share pointers, slices or maps.
+	return func() reflect.Value {
+		v, _ := parseText(text, t)
+		return v
+	}, nil
+}
+
+func parseText(text string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	if t.Kind() == reflect.Pointer {
+		elem, err := parseText(text, t.Elem())
+		if err != nil {
+			return v, err
+		}
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(elem)
+		return v, nil
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return v, v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+	if t == durationType {
+		d, err := time.ParseDuration(text)
+		v.SetInt(int64(d))
+		return v, err
+	}
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 0, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(text, 0, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(text, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetComplex(c)
+	default:
+		if err := json.Unmarshal([]byte(text), v.Addr().Interface()); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+// Cached default tags to avoid re-parsing them for every plan.
+type tagDefaults struct {
+	defaults []fieldDefault
+	err      error
+}
+
+var defaultsCache = newLRUCache[fieldMapKey, tagDefaults](DefaultFieldCacheLimit)
+
+// getDefaults returns the parsed default tags of the exported fields of the
+// struct type t, including promoted fields of embedded structs. A default=
+// option in the typeconv tag takes precedence over the tag named by tag.
+func getDefaults(t reflect.Type, tag string) ([]fieldDefault, error) {
+	td, _ := defaultsCache.get(fieldMapKey{t: t, tag: tag}, func() (tagDefaults, error) {
+		return parseDefaults(t, tag), nil
+	})
+	return td.defaults, td.err
+}
+
+func parseDefaults(t reflect.Type, tag string) tagDefaults {
+	var td tagDefaults
+	var walk func(rt reflect.Type, path []int)
+	walk = func(rt reflect.Type, path []int) {
+		for i := 0; i < rt.NumField() && td.err == nil; i++ {
+			f := rt.Field(i)
+			idx := append(append([]int{}, path...), i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, idx)
+				continue
+			}
+			var text string
+			ok := false
+			if tag != "-" {
+				text, ok = f.Tag.Lookup(tag)
+			}
+			if tv, has := f.Tag.Lookup(typeconvTag); has {
+				// Malformed typeconv tags are reported by the field map.
+				if ft, err := parseTypeconvTag(tv); err == nil && ft.hasDef {
+					text, ok = ft.def, true
+				}
+			}
+			if !ok || f.PkgPath != "" {
+				continue
+			}
+			fn, err := parseDefault(text, f.Type)
+			if err != nil {
+				td.err = fmt.Errorf("invalid %s tag on %s.%s: %w", tag, rt, f.Name, err)
+				return
+			}
+			td.defaults = append(td.defaults, fieldDefault{dstIndex: idx, name: f.Name, value: fn})
+		}
+	}
+	walk(t, nil)
+	return td
+}
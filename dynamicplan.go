@@ -1,40 +1,512 @@
 package typeconv
 
 import (
+	"cmp"
+	"context"
+	"fmt"
 	"reflect"
+	"slices"
+	"unsafe"
 )
 
 type dynamicPlan struct {
-	steps []step
-	opts  Options
+	// src and dst are the struct types the plan converts between.
+	src, dst reflect.Type
+	steps    []step
+	opts     Options
+	// before and after record whether the destination implements
+	// BeforeConverter or AfterConverter.
+	before, after bool
+	// hooks are the registered After hooks for this pair.
+	hooks []converterCall
+	// computed fields run after steps, before AfterConvert and hooks.
+	computed []computed
+	// defaults fill destination fields that have no step and are still
+	// zero once the steps have run.
+	defaults []fieldDefault
+	// copies records whether any step has an offset copy.
+	copies bool
+	// direct plans convert the whole struct at once; see directConvertible.
+	direct bool
 }
 
-func buildDynamicPlan(st, dt reflect.Type, opts Options) (*dynamicPlan, error) {
-	smap := getFieldMap(st, opts.Tag)
-	dmap := getFieldMap(dt, opts.Tag)
+// compiler turns type pairs into dynamic plans with precompiled leaf
+// converters. Plans are memoized per pair, which also terminates recursion
+// through self-referencing types.
+//
+// Field extensions are addressed by destination path, so a pair reached at a
+// path with extensions below it is compiled separately for that path; every
+// other occurrence of the pair shares one plan.
+type compiler struct {
+	opts   Options
+	reg    *localConverterRegistry
+	plans  map[scopedKey]*dynamicPlan
+	leaves map[scopedKey]leafConv
+	rules  map[scopedKey]*leafRule
+	// used records the field extension paths that matched a step.
+	used map[string]bool
+	// invalid collects the fields that have no valid conversion rule,
+	// anywhere in the type graph, so that root can report all of them.
+	invalid []error
+	// added logs the keys memoized in plans and leaves, in order, so that
+	// a fallback compiled only for a conversion hook can be dropped.
+	added []scopedKey
+	// customs memoizes registry lookups by pointer-free pair, so a factory
+	// is consulted once per pair however many pointer variants appear.
+	customs map[convKey]leafConv
+}
+
+func newCompiler(opts Options, reg *localConverterRegistry) *compiler {
+	return &compiler{
+		opts:   opts,
+		reg:    reg,
+		plans:  make(map[scopedKey]*dynamicPlan),
+		leaves: make(map[scopedKey]leafConv),
+		rules:  make(map[scopedKey]*leafRule),
+		used:   make(map[string]bool),
+	}
+}
+
+type scopedKey struct {
+	convKey
+	scope string
+}
+
+// key returns the memoization key for st -> dt reached at path.
+func (c *compiler) key(st, dt reflect.Type, path string) scopedKey {
+	k := scopedKey{convKey: convKey{src: st, dst: dt}}
+	if c.reg.hasFieldsUnder(path) {
+		k.scope = "@" + path
+	}
+	return k
+}
+
+// root compiles the top-level plan for st -> dt and verifies that every
+// field extension matched a destination field.
+func (c *compiler) root(st, dt reflect.Type) (*dynamicPlan, error) {
+	p, err := c.plan(st, dt, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(c.invalid) > 0 {
+		return nil, &PlanError{Errs: c.invalid}
+	}
+	if c.reg != nil {
+		for _, path := range c.reg.fieldPaths() {
+			if !c.used[path] {
+				return nil, fmt.Errorf("no mapped destination field at path %q", path)
+			}
+		}
+	}
+	return p, nil
+}
+
+// rollback drops the invalid fields and memoized plans and leaves recorded
+// after the marks invalid and added, and returns the dropped errors.
+func (c *compiler) rollback(invalid, added int) []error {
+	errs := slices.Clone(c.invalid[invalid:])
+	c.invalid = c.invalid[:invalid]
+	for _, k := range c.added[added:] {
+		delete(c.plans, k)
+		delete(c.leaves, k)
+		delete(c.rules, k)
+	}
+	c.added = c.added[:added]
+	return errs
+}
+
+// custom returns the registered converter for st -> dt, or nil.
+func (c *compiler) custom(st, dt reflect.Type) leafConv {
+	if c.reg.empty() {
+		return nil
+	}
+	for st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	for dt.Kind() == reflect.Pointer {
+		dt = dt.Elem()
+	}
+	key := convKey{src: st, dst: dt}
+	if cv, ok := c.customs[key]; ok {
+		return cv
+	}
+	cv, _ := c.reg.lookup(st, dt)
+	if c.customs == nil {
+		c.customs = make(map[convKey]leafConv)
+	}
+	c.customs[key] = cv
+	return cv
+}
+
+// plan returns the dynamic plan for st -> dt reached at path. The plan is
+// registered before its steps are compiled so that recursive pairs resolve
+// to the same plan.
+func (c *compiler) plan(st, dt reflect.Type, path string) (*dynamicPlan, error) {
+	key := c.key(st, dt, path)
+	if p, ok := c.plans[key]; ok {
+		return p, nil
+	}
+	sfields := getFields(st, c.opts.Tag)
+	dfields := getFields(dt, c.opts.Tag)
+	if sfields.err != nil {
+		return nil, sfields.err
+	}
+	if dfields.err != nil {
+		return nil, dfields.err
+	}
 	var steps []step
-	for name, sfi := range smap {
-		if dfi, ok := dmap[name]; ok {
+	for name, dfi := range dfields.index {
+		dtag := dfields.tags[name]
+		if dtag.dir == dirRead {
+			continue
+		}
+		if sname, ok := sfields.sourceFor(name, dfields); ok {
+			sfi, stag := sfields.index[sname], sfields.tags[sname]
 			stLeaf := st.FieldByIndex(sfi).Type
-			dtLeaf := dt.FieldByIndex(dfi).Type
-			steps = append(steps, step{srcIndex: sfi, dstIndex: dfi, srcType: stLeaf, dstType: dtLeaf})
+			dtField := dt.FieldByIndex(dfi)
+			dtLeaf := dtField.Type
+			steps = append(steps, step{srcIndex: sfi, dstIndex: dfi, srcType: stLeaf, dstType: dtLeaf, name: dtField.Name,
+				format: cmp.Or(dtag.format, stag.format), convName: cmp.Or(dtag.conv, stag.conv), required: dtag.required})
 		}
 	}
-	if len(steps) == 0 {
+	// Run steps in destination declaration order, so conversions are
+	// deterministic and predicates see the fields declared before theirs.
+	slices.SortFunc(steps, func(a, b step) int { return slices.Compare(a.dstIndex, b.dstIndex) })
+	computedPaths := c.reg.childrenUnder(path, func(fc *fieldConfig) bool { return fc.compute != nil })
+	if len(steps) == 0 && len(computedPaths) == 0 {
 		return nil, errNoOverlappingJSONTaggedFields
 	}
-	return &dynamicPlan{steps: steps, opts: opts}, nil
+	p := &dynamicPlan{src: st, dst: dt, steps: steps, opts: c.opts}
+	for _, cp := range computedPaths {
+		cf, err := c.computedField(st, dt, steps, cp)
+		if err != nil {
+			return nil, err
+		}
+		p.computed = append(p.computed, cf)
+	}
+	if err := c.defaults(p, dt, path); err != nil {
+		return nil, err
+	}
+	if err := c.required(p, dt, dfields, path); err != nil {
+		return nil, err
+	}
+	dtPtr := reflect.PointerTo(dt)
+	p.before = dtPtr.Implements(beforeConverterType)
+	p.after = dtPtr.Implements(afterConverterType)
+	p.hooks = c.reg.afterHooks(st, dt)
+	c.plans[key] = p
+	c.added = append(c.added, key)
+	for i := range p.steps {
+		s := &p.steps[i]
+		fieldPath := joinPath(path, s.name)
+		conv, rule, err := c.stepConv(s, fieldPath)
+		if err == nil {
+			err = c.fieldExtensions(s, st, dt, fieldPath)
+		}
+		if err != nil {
+			// Keep going, so that one plan reports every invalid field.
+			c.invalid = append(c.invalid, err)
+			continue
+		}
+		s.conv, s.rule = conv, rule
+		if c.opts.UnsafeCopy {
+			s.copy = fastCopy(st, dt, s)
+			p.copies = p.copies || s.copy != nil
+		}
+	}
+	p.direct = p.directConvertible()
+	return p, nil
+}
+
+// directConvertible reports whether a Go conversion of the whole struct
+// gives the same result as running p: the types are convertible, and every
+// field is assigned from the field at the same position with nothing else
+// to run. Pointer fields are excluded, since assignment gives them a fresh
+// destination rather than sharing the source's.
+func (p *dynamicPlan) directConvertible() bool {
+	if !p.src.ConvertibleTo(p.dst) || p.before || p.after || len(p.hooks) > 0 || len(p.computed) > 0 || len(p.defaults) > 0 {
+		return false
+	}
+	mapped := make(map[string]bool, len(p.steps))
+	for _, s := range p.steps {
+		if s.rule == nil || s.rule.rule != RuleAssign || s.srcType != s.dstType || s.dstType.Kind() == reflect.Pointer ||
+			!slices.Equal(s.srcIndex, s.dstIndex) || len(s.conds) > 0 || len(s.transforms) > 0 || s.def != nil || s.required {
+			return false
+		}
+		mapped[fmt.Sprint(s.dstIndex)] = true
+	}
+	return coversFields(p.dst, nil, mapped)
+}
+
+// coversFields reports whether every field of struct type t below index is
+// mapped, itself or through the fields of an embedded struct value.
+func coversFields(t reflect.Type, index []int, mapped map[string]bool) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f, idx := t.Field(i), append(slices.Clone(index), i)
+		if mapped[fmt.Sprint(idx)] {
+			continue
+		}
+		if !f.Anonymous || f.Type.Kind() != reflect.Struct || !coversFields(f.Type, idx, mapped) {
+			return false
+		}
+	}
+	return true
+}
+
+// defaults attaches the default tags of dt and the plan-level defaults below
+// path to the steps of p, or to p itself for fields without a step.
+func (c *compiler) defaults(p *dynamicPlan, dt reflect.Type, path string) error {
+	defs, err := getDefaults(dt, c.opts.DefaultTag)
+	if err != nil {
+		return err
+	}
+	for _, dp := range c.reg.childrenUnder(path, func(fc *fieldConfig) bool { return fc.def != nil }) {
+		f, err := destField(dt, dp)
+		if err != nil {
+			return fmt.Errorf("default for %q: %w", dp, err)
+		}
+		value, err := c.reg.field(dp).def.resolve(f.Type)
+		if err != nil {
+			return err
+		}
+		c.used[dp] = true
+		defs = append(slices.DeleteFunc(slices.Clone(defs), func(d fieldDefault) bool {
+			return slices.Equal(d.dstIndex, f.Index)
+		}), fieldDefault{dstIndex: f.Index, name: f.Name, value: value})
+	}
+	for _, d := range defs {
+		if i := slices.IndexFunc(p.steps, func(s step) bool { return slices.Equal(s.dstIndex, d.dstIndex) }); i >= 0 {
+			p.steps[i].def = d.value
+			continue
+		}
+		if slices.ContainsFunc(p.computed, func(cf computed) bool { return slices.Equal(cf.dstIndex, d.dstIndex) }) {
+			continue
+		}
+		p.defaults = append(p.defaults, d)
+	}
+	return nil
+}
+
+// required marks the steps of p whose destination is required, through the
+// "required" tag option or Required, and fails for a required field that has
+// neither a source counterpart nor a computed value.
+func (c *compiler) required(p *dynamicPlan, dt reflect.Type, dfields *fieldMap, path string) error {
+	var want []reflect.StructField
+	for name, idx := range dfields.index {
+		if ft := dfields.tags[name]; ft.required && ft.dir != dirRead {
+			want = append(want, dt.FieldByIndex(idx))
+		}
+	}
+	for _, rp := range c.reg.childrenUnder(path, func(fc *fieldConfig) bool { return fc.required }) {
+		f, err := destField(dt, rp)
+		if err != nil {
+			return fmt.Errorf("required field %q: %w", rp, err)
+		}
+		c.used[rp] = true
+		want = append(want, f)
+	}
+	slices.SortFunc(want, func(a, b reflect.StructField) int { return slices.Compare(a.Index, b.Index) })
+	for _, f := range want {
+		if i := slices.IndexFunc(p.steps, func(s step) bool { return slices.Equal(s.dstIndex, f.Index) }); i >= 0 {
+			p.steps[i].required = true
+			continue
+		}
+		if slices.ContainsFunc(p.computed, func(cf computed) bool { return slices.Equal(cf.dstIndex, f.Index) }) {
+			continue
+		}
+		return fmt.Errorf("required field %q has no source field in %s", joinPath(path, f.Name), dt)
+	}
+	return nil
+}
+
+// computedField resolves the computed field at path against st -> dt.
+func (c *compiler) computedField(st, dt reflect.Type, steps []step, path string) (computed, error) {
+	fc := c.reg.field(path).compute
+	f, err := destField(dt, path)
+	if err != nil {
+		return computed{}, fmt.Errorf("computed field %q: %w", path, err)
+	}
+	for _, s := range steps {
+		if slices.Equal(s.dstIndex, f.Index) {
+			return computed{}, fmt.Errorf("computed field %q collides with field mapped from source", path)
+		}
+	}
+	if fc.src != st {
+		return computed{}, fmt.Errorf("computed field %q must take source %s, got %s", path, st, fc.src)
+	}
+	if fc.dst != nil && fc.dst != dt {
+		return computed{}, fmt.Errorf("computed field %q must take destination *%s, got *%s", path, dt, fc.dst)
+	}
+	if fc.out != f.Type {
+		return computed{}, fmt.Errorf("computed field %q must return %s, got %s", path, f.Type, fc.out)
+	}
+	conds, err := c.reg.field(path).conditions(path, st, dt)
+	if err != nil {
+		return computed{}, err
+	}
+	c.used[path] = true
+	return computed{dstIndex: f.Index, name: f.Name, compute: fc, conds: conds}, nil
+}
+
+// stepConv compiles the converter of s: the converter named by its tag, a
+// tag format, or the leaf converter for its types.
+func (c *compiler) stepConv(s *step, fieldPath string) (leafConv, *leafRule, error) {
+	switch {
+	case s.convName != "":
+		conv, err := c.reg.namedConv(s.convName, s.srcType, s.dstType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %q: %w", fieldPath, err)
+		}
+		if c.opts.Observer != nil {
+			conv = observedConv(EventCustom, s.srcType, s.dstType, conv)
+		}
+		return conv, &leafRule{rule: RuleNamed, name: s.convName}, nil
+	case s.format != "":
+		conv, err := formatConv(s.srcType, s.dstType, s.format)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %q: %w", fieldPath, err)
+		}
+		return conv, &leafRule{rule: RuleFormat, name: s.format}, nil
+	}
+	conv, rule, err := c.leaf(s.srcType, s.dstType, fieldPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("field %q: %w", fieldPath, err)
+	}
+	return conv, rule, nil
+}
+
+// fieldExtensions attaches the extensions registered for fieldPath to s, a
+// step of the pair st -> dt.
+func (c *compiler) fieldExtensions(s *step, st, dt reflect.Type, fieldPath string) error {
+	fc := c.reg.field(fieldPath)
+	if fc == nil {
+		return nil
+	}
+	c.used[normPath(fieldPath)] = true
+	conds, err := fc.conditions(fieldPath, st, dt)
+	if err != nil {
+		return err
+	}
+	s.conds = conds
+	for _, t := range fc.transforms {
+		if t.typ != s.dstType {
+			return fmt.Errorf("transform for %q must convert %s, got %s", fieldPath, s.dstType, t.typ)
+		}
+		s.transforms = append(s.transforms, t.call)
+	}
+	return nil
 }
 
-func (p *dynamicPlan) run(dst, src reflect.Value, reg localConverterRegistry) error {
+func (p *dynamicPlan) run(ctx context.Context, dst, src reflect.Value) error {
+	if p.direct {
+		if src.CanAddr() {
+			// Reinterpreting the source avoids the copy Convert allocates.
+			dst.Set(reflect.NewAt(p.dst, src.Addr().UnsafePointer()).Elem())
+		} else {
+			dst.Set(src.Convert(p.dst))
+		}
+		return nil
+	}
+	if p.before {
+		if err := dst.Addr().Interface().(BeforeConverter).BeforeConvert(src.Interface()); err != nil {
+			return err
+		}
+	}
+	var dp, sp unsafe.Pointer
+	if p.copies && src.CanAddr() {
+		dp, sp = dst.Addr().UnsafePointer(), src.Addr().UnsafePointer()
+	}
+	var ob *observation
+	if p.opts.Observer != nil {
+		ob = observing(ctx)
+	}
 	for _, s := range p.steps {
-		conv, err := makeLeafConv(s.srcType, s.dstType, p.opts, reg)
+		if s.copy != nil && sp != nil {
+			s.copy.copy(dp, sp)
+			continue
+		}
+		if !allowed(s.conds, src, dst) {
+			continue
+		}
+		svLeaf, err := src.FieldByIndexErr(s.srcIndex)
+		if err != nil {
+			// A nil embedded pointer: the field is nil like a nil source.
+			svLeaf = reflect.Zero(s.srcType)
+			if !s.required && s.def == nil {
+				if dv, err := dst.FieldByIndexErr(s.dstIndex); err == nil && dv.CanSet() {
+					dv.SetZero()
+				}
+				continue
+			}
+		}
+		dvLeaf := fieldByIndexAlloc(dst, s.dstIndex)
+		if !dvLeaf.CanSet() {
+			return fmt.Errorf("destination field not settable at %v", s.dstIndex)
+		}
+		if s.required && svLeaf.IsZero() {
+			return withField(s.name, ErrRequired)
+		}
+		if s.def != nil && svLeaf.IsZero() {
+			dvLeaf.Set(s.def())
+			continue
+		}
+		ob.push(fieldSegment(s.name))
+		err = s.conv(ctx, dvLeaf, svLeaf)
+		ob.pop()
+		if err != nil {
+			return withField(s.name, err)
+		}
+		for _, t := range s.transforms {
+			cur := reflect.New(s.dstType).Elem()
+			cur.Set(dvLeaf)
+			if err := t(ctx, cur, dvLeaf); err != nil {
+				return withField(s.name, err)
+			}
+		}
+	}
+	for _, d := range p.defaults {
+		if dv, err := dst.FieldByIndexErr(d.dstIndex); err != nil || dv.IsZero() {
+			fieldByIndexAlloc(dst, d.dstIndex).Set(d.value())
+		}
+	}
+	for _, cf := range p.computed {
+		if !allowed(cf.conds, src, dst) {
+			continue
+		}
+		v, err := cf.compute.call(ctx, src, dst)
 		if err != nil {
+			return withField(cf.name, err)
+		}
+		fieldByIndexAlloc(dst, cf.dstIndex).Set(v)
+	}
+	if p.after {
+		if err := dst.Addr().Interface().(AfterConverter).AfterConvert(src.Interface()); err != nil {
 			return err
 		}
-		if err := conv(dst.FieldByIndex(s.dstIndex), src.FieldByIndex(s.srcIndex)); err != nil {
+	}
+	for _, h := range p.hooks {
+		if err := h(ctx, addressable(src), dst); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// fieldByIndexAlloc is v.FieldByIndex, allocating the nil embedded pointers
+// on the way as destination pointers are. It returns the zero Value if one
+// of them cannot be set.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
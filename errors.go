@@ -0,0 +1,73 @@
+package typeconv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrRequired is returned, wrapped in a *FieldError, when the source of a
+// required destination field is nil or zero.
+var ErrRequired = errors.New("required field is nil or zero")
+
+// PlanError is returned by BuildPlan and Convert when fields have no valid
+// conversion rule. Errs holds one error per field, with its path, for every
+// field in the type graph, not just the first one found.
+type PlanError struct {
+	Errs []error
+}
+
+func (e *PlanError) Error() string {
+	if len(e.Errs) == 1 {
+		return e.Errs[0].Error()
+	}
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d fields cannot be converted: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+func (e *PlanError) Unwrap() []error { return e.Errs }
+
+// FieldError reports a conversion failure together with the destination
+// path at which it happened, e.g. "Items[2].Price". Err is the underlying
+// cause, so errors.Is and errors.As see through it.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string { return e.Path + ": " + e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// withField prefixes the path of err with a struct field name.
+func withField(name string, err error) error {
+	return withSegment(name, err)
+}
+
+// withIndex prefixes the path of err with a slice index.
+func withIndex(i int, err error) error {
+	return withSegment("["+strconv.Itoa(i)+"]", err)
+}
+
+// withKey prefixes the path of err with a map key.
+func withKey(k reflect.Value, err error) error {
+	return withSegment(fmt.Sprintf("[%v]", k.Interface()), err)
+}
+
+func withSegment(seg string, err error) error {
+	fe, ok := err.(*FieldError)
+	if !ok {
+		return &FieldError{Path: seg, Err: err}
+	}
+	if strings.HasPrefix(fe.Path, "[") {
+		fe.Path = seg + fe.Path
+	} else {
+		fe.Path = seg + "." + fe.Path
+	}
+	return fe
+}
@@ -0,0 +1,211 @@
+package typeconv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Rule names the conversion rule a plan chose for a field.
+type Rule string
+
+const (
+	RuleCustom   Rule = "custom"   // registered converter or factory
+	RuleNamed    Rule = "named"    // converter selected with conv= in the tag
+	RuleFormat   Rule = "format"   // format= tag option
+	RuleHook     Rule = "hook"     // ConvertFrom / ConvertTo on the types
+	RuleAssign   Rule = "assign"   // identical or assignable types
+	RuleStruct   Rule = "struct"   // field-by-field struct recursion
+	RuleSlice    Rule = "slice"    // element-wise slice conversion
+	RuleMap      Rule = "map"      // element-wise map conversion
+	RuleConvert  Rule = "convert"  // reflect.Value.Convert
+	RuleText     Rule = "text"     // MarshalText / UnmarshalText
+	RuleParse    Rule = "parse"    // strconv parsing and formatting
+	RuleJSON     Rule = "json"     // JSON round trip
+	RuleComputed Rule = "computed" // Compute
+	RuleDefault  Rule = "default"  // default value of a field without a source
+)
+
+// leafRule records how a leaf converter was chosen.
+type leafRule struct {
+	rule Rule
+	// name is the named converter or format.
+	name string
+	// next is the rule used when a conversion hook declines.
+	next *leafRule
+	// elem describes the elements of a slice or the values of a map.
+	elem *leafRule
+	// plan is the nested plan of a struct rule.
+	plan *dynamicPlan
+}
+
+// PlanDescription is a read-only description of a compiled plan. It
+// marshals to JSON as is; String renders it as text.
+type PlanDescription struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	// Direct marks a plan converting the whole struct with one Go
+	// conversion, because the types share their layout and field mapping.
+	Direct bool               `json:"direct,omitempty"`
+	Fields []FieldDescription `json:"fields"`
+}
+
+// FieldDescription describes how one destination field is filled. Nested
+// fields are listed after their parent with dotted paths; slice elements and
+// map values are addressed as Field[].
+type FieldDescription struct {
+	// Source is empty for computed fields and defaults.
+	Source          string `json:"source,omitempty"`
+	Destination     string `json:"destination"`
+	SourceType      string `json:"sourceType,omitempty"`
+	DestinationType string `json:"destinationType"`
+	Rule            Rule   `json:"rule"`
+	// Fallback is the rule used when a conversion hook declines the value.
+	Fallback Rule `json:"fallback,omitempty"`
+	// Converter is the converter name or format of RuleNamed and RuleFormat.
+	Converter   string `json:"converter,omitempty"`
+	Conditional bool   `json:"conditional,omitempty"`
+	Transforms  int    `json:"transforms,omitempty"`
+	Default     bool   `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Recursive marks a struct already being described further up, whose
+	// fields are not listed again.
+	Recursive bool `json:"recursive,omitempty"`
+	// Direct marks a struct converted with one Go conversion.
+	Direct bool `json:"direct,omitempty"`
+}
+
+// Describe returns a description of the rule used for every field of the
+// plan, recursing through nested structs, slice elements and map values.
+func (p *Plan[S, D]) Describe() *PlanDescription {
+	w := describer{fields: []FieldDescription{}}
+	w.plan(p.root, "", "")
+	return &PlanDescription{
+		Source:      reflect.TypeOf((*S)(nil)).Elem().String(),
+		Destination: reflect.TypeOf((*D)(nil)).Elem().String(),
+		Direct:      p.root.direct,
+		Fields:      w.fields,
+	}
+}
+
+// Explain renders Describe as text, one field per line.
+func (p *Plan[S, D]) Explain() string {
+	return p.Describe().String()
+}
+
+// String renders the description as an aligned table.
+func (d *PlanDescription) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s -> %s", d.Source, d.Destination)
+	if d.Direct {
+		b.WriteString(" (direct)")
+	}
+	b.WriteString("\n")
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, f := range d.Fields {
+		src, types := f.Source, f.DestinationType
+		if src == "" {
+			src = "-"
+		} else {
+			types = f.SourceType + " -> " + types
+		}
+		rule := string(f.Rule)
+		if f.Converter != "" {
+			rule += " " + f.Converter
+		}
+		if f.Fallback != "" {
+			rule += ", else " + string(f.Fallback)
+		}
+		var notes []string
+		for _, n := range []struct {
+			set  bool
+			text string
+		}{
+			{f.Conditional, "conditional"},
+			{f.Transforms > 0, "transformed"},
+			{f.Default, "default"},
+			{f.Required, "required"},
+			{f.Recursive, "recursive"},
+			{f.Direct, "direct"},
+		} {
+			if n.set {
+				notes = append(notes, n.text)
+			}
+		}
+		if len(notes) > 0 {
+			rule += " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Fprintf(tw, "  %s\t-> %s\t%s\t%s\n", src, f.Destination, types, rule)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+type describer struct {
+	fields []FieldDescription
+	// active holds the plans being described, to stop at recursive types.
+	active []*dynamicPlan
+}
+
+func (w *describer) plan(p *dynamicPlan, srcPrefix, dstPrefix string) {
+	w.active = append(w.active, p)
+	defer func() { w.active = w.active[:len(w.active)-1] }()
+	for _, s := range p.steps {
+		w.leaf(FieldDescription{
+			Source:      srcPrefix + p.src.FieldByIndex(s.srcIndex).Name,
+			Destination: dstPrefix + s.name,
+			Conditional: len(s.conds) > 0,
+			Transforms:  len(s.transforms),
+			Default:     s.def != nil,
+			Required:    s.required,
+		}, s.srcType, s.dstType, s.rule)
+	}
+	for _, cf := range p.computed {
+		w.fields = append(w.fields, FieldDescription{
+			Destination:     dstPrefix + cf.name,
+			DestinationType: cf.compute.out.String(),
+			Rule:            RuleComputed,
+			Conditional:     len(cf.conds) > 0,
+		})
+	}
+	for _, d := range p.defaults {
+		w.fields = append(w.fields, FieldDescription{
+			Destination:     dstPrefix + d.name,
+			DestinationType: p.dst.FieldByIndex(d.dstIndex).Type.String(),
+			Rule:            RuleDefault,
+			Default:         true,
+		})
+	}
+}
+
+// leaf appends f, converting st -> dt with r, followed by its nested fields.
+func (w *describer) leaf(f FieldDescription, st, dt reflect.Type, r *leafRule) {
+	f.SourceType, f.DestinationType = st.String(), dt.String()
+	f.Rule, f.Converter = r.rule, r.name
+	if r.next != nil {
+		f.Fallback = r.next.rule
+		r = r.next
+	}
+	if r.plan != nil {
+		f.Direct = r.plan.direct
+		for _, p := range w.active {
+			if p == r.plan {
+				f.Recursive = true
+			}
+		}
+	}
+	w.fields = append(w.fields, f)
+	for st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	for dt.Kind() == reflect.Pointer {
+		dt = dt.Elem()
+	}
+	switch {
+	case r.plan != nil && !f.Recursive:
+		w.plan(r.plan, f.Source+".", f.Destination+".")
+	case r.elem != nil:
+		w.leaf(FieldDescription{Source: f.Source + "[]", Destination: f.Destination + "[]"}, st.Elem(), dt.Elem(), r.elem)
+	}
+}
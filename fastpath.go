@@ -0,0 +1,136 @@
+package typeconv
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// fieldCopy copies one field between structs at precomputed offsets,
+// bypassing FieldByIndex and the leaf converter. It is only used for steps
+// that plain assignment would convert, with Options.UnsafeCopy set.
+type fieldCopy struct {
+	src, dst uintptr
+	size     uintptr
+	kind     copyKind
+	// word marks copyBytes fields aligned for a single word-sized move.
+	word bool
+	// typ is the field type of copyTyped.
+	typ reflect.Type
+}
+
+type copyKind uint8
+
+const (
+	// copyBytes copies pointer-free memory.
+	copyBytes copyKind = iota
+	// copyString assigns a string header.
+	copyString
+	// copyTyped copies a value containing pointers through reflect, which
+	// keeps the garbage collector's write barriers.
+	copyTyped
+)
+
+// fastCopy returns the offset copy for s, a step of the pair st -> dt, or
+// nil if s must go through its converter.
+func fastCopy(st, dt reflect.Type, s *step) *fieldCopy {
+	if s.rule == nil || (s.rule.rule != RuleAssign && s.rule.rule != RuleConvert) ||
+		len(s.conds) > 0 || len(s.transforms) > 0 || s.def != nil || s.required {
+		return nil
+	}
+	srcOff, ok := fieldOffset(st, s.srcIndex)
+	if !ok {
+		return nil
+	}
+	dstOff, ok := fieldOffset(dt, s.dstIndex)
+	if !ok {
+		return nil
+	}
+	f := &fieldCopy{src: srcOff, dst: dstOff, size: s.dstType.Size()}
+	sk, dk := s.srcType.Kind(), s.dstType.Kind()
+	switch {
+	case sk != dk || s.srcType.Size() != f.size:
+		return nil
+	case pointerFree(s.srcType) && (s.srcType == s.dstType || isScalar(sk)):
+		f.kind = copyBytes
+		f.word = uintptr(s.srcType.Align()) >= f.size && uintptr(s.dstType.Align()) >= f.size
+	case sk == reflect.String:
+		f.kind = copyString
+	case s.srcType == s.dstType && sk != reflect.Pointer:
+		// Assignment allocates a fresh destination for pointers rather
+		// than sharing the source's; every other kind is copied as is.
+		f.kind, f.typ = copyTyped, s.dstType
+	default:
+		return nil
+	}
+	return f
+}
+
+// fieldOffset returns the offset of the field at index in struct type t, or
+// false if the path crosses an embedded pointer, which has no fixed offset.
+func fieldOffset(t reflect.Type, index []int) (uintptr, bool) {
+	var off uintptr
+	for _, x := range index {
+		if t.Kind() == reflect.Pointer {
+			return 0, false
+		}
+		f := t.Field(x)
+		off += f.Offset
+		t = f.Type
+	}
+	return off, true
+}
+
+// pointerFree reports whether values of t contain no pointers, so that
+// their memory can be copied without write barriers.
+func pointerFree(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return t.Len() == 0 || pointerFree(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !pointerFree(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return isScalar(t.Kind())
+}
+
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// copy copies the field from the struct at src to the struct at dst.
+func (f *fieldCopy) copy(dst, src unsafe.Pointer) {
+	d, s := unsafe.Add(dst, f.dst), unsafe.Add(src, f.src)
+	switch f.kind {
+	case copyString:
+		*(*string)(d) = *(*string)(s)
+	case copyTyped:
+		reflect.NewAt(f.typ, d).Elem().Set(reflect.NewAt(f.typ, s).Elem())
+	default:
+		if !f.word {
+			copy(unsafe.Slice((*byte)(d), f.size), unsafe.Slice((*byte)(s), f.size))
+			return
+		}
+		switch f.size {
+		case 8:
+			*(*uint64)(d) = *(*uint64)(s)
+		case 4:
+			*(*uint32)(d) = *(*uint32)(s)
+		case 2:
+			*(*uint16)(d) = *(*uint16)(s)
+		case 1:
+			*(*uint8)(d) = *(*uint8)(s)
+		default:
+			copy(unsafe.Slice((*byte)(d), f.size), unsafe.Slice((*byte)(s), f.size))
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package typeconv
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fieldConfig collects the extensions registered for one destination path.
+type fieldConfig struct {
+	transforms []fieldTransform
+	compute    *fieldCompute
+	conds      []fieldCond
+	def        *defaultExt
+	required   bool
+}
+
+// fieldCond is a predicate over the source and destination structs owning a
+// field; the field is only converted when it holds.
+type fieldCond struct {
+	src, dst reflect.Type
+	call     condition
+}
+
+// condition receives the owning source struct and the addressable owning
+// destination struct.
+type condition func(src, dst reflect.Value) bool
+
+type fieldTransform struct {
+	typ  reflect.Type
+	call converterCall
+}
+
+// fieldCompute computes a destination field from the whole source struct.
+type fieldCompute struct {
+	src, dst, out reflect.Type
+	// call receives the source struct and the addressable destination struct.
+	call func(ctx context.Context, src, dst reflect.Value) (reflect.Value, error)
+}
+
+// computed is a compiled computed field of a dynamic plan.
+type computed struct {
+	dstIndex []int
+	name     string
+	compute  *fieldCompute
+	conds    []condition
+}
+
+// transformExt marks a per-field transform. See Transform.
+type transformExt struct {
+	path string
+	fn   any
+}
+
+// Transform registers fn to run on the destination field at path after it
+// has been converted, e.g. Transform("Email", strings.ToLower). path is a
+// dot-separated list of destination field names, matched case-insensitively;
+// slice elements and map values are addressed through their field, so
+// "Lines.Total" applies to the Total of every element of Lines. fn may have
+// any converter shape whose source and destination are the field's type.
+// A path that does not name a mapped destination field fails planning.
+func Transform(path string, fn any) any {
+	return transformExt{path: path, fn: fn}
+}
+
+// computeExt marks a computed field. See Compute.
+type computeExt struct {
+	path string
+	fn   any
+}
+
+// Compute declares the destination field at path as computed from the whole
+// source struct that owns it, for fields with no single source counterpart.
+// fn is one of
+//
+//	func(src S) T
+//	func(src S) (T, error)
+//	func(src S, dst *D) (T, error)
+//
+// where S (or *S) is the source struct, D the destination struct containing
+// the field and T the field's type. Computed fields run after the automatic
+// mapping of D, so dst is already filled. path follows the rules of
+// Transform; the field may be untagged or excluded from mapping, but a field
+// that is also mapped automatically fails planning.
+func Compute(path string, fn any) any {
+	return computeExt{path: path, fn: fn}
+}
+
+func (r *localConverterRegistry) addCompute(e computeExt) error {
+	rv := reflect.ValueOf(e.fn)
+	rt := reflect.TypeOf(e.fn)
+	bad := fmt.Errorf("computed field %q must be func(S) T, func(S) (T, error) or func(S, *D) (T, error), got %T", e.path, e.fn)
+	if rt == nil || rt.Kind() != reflect.Func || rt.IsVariadic() {
+		return bad
+	}
+	if rt.NumIn() < 1 || rt.NumIn() > 2 || rt.NumOut() < 1 || rt.NumOut() > 2 {
+		return bad
+	}
+	if rt.NumOut() == 2 && rt.Out(1) != errorType || rt.NumIn() == 2 && (rt.In(1).Kind() != reflect.Pointer || rt.NumOut() != 2) {
+		return bad
+	}
+	fc := &fieldCompute{src: rt.In(0), out: rt.Out(0)}
+	byPtr := fc.src.Kind() == reflect.Pointer
+	if byPtr {
+		fc.src = fc.src.Elem()
+	}
+	if rt.NumIn() == 2 {
+		fc.dst = rt.In(1).Elem()
+	}
+	fc.call = func(ctx context.Context, src, dst reflect.Value) (reflect.Value, error) {
+		if byPtr {
+			src = addressable(src).Addr()
+		}
+		args := []reflect.Value{src}
+		if fc.dst != nil {
+			args = append(args, dst.Addr())
+		}
+		out := rv.Call(args)
+		if len(out) == 2 {
+			if err := errorResult(out[1]); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		return out[0], nil
+	}
+	cfg := r.fieldFor(e.path)
+	if cfg.compute != nil {
+		return fmt.Errorf("duplicate computed field %q", e.path)
+	}
+	cfg.compute = fc
+	return nil
+}
+
+// childrenUnder returns the normalized paths directly below path whose
+// extensions satisfy keep, in sorted order.
+func (r *localConverterRegistry) childrenUnder(path string, keep func(*fieldConfig) bool) []string {
+	if r == nil {
+		return nil
+	}
+	parent := normPath(path)
+	var out []string
+	for _, p := range r.fieldPaths() {
+		if !keep(r.fields[p]) {
+			continue
+		}
+		dir := ""
+		if i := strings.LastIndexByte(p, '.'); i >= 0 {
+			dir = p[:i]
+		}
+		if dir == parent {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// destField resolves the last segment of path to an exported field of dt.
+func destField(dt reflect.Type, path string) (reflect.StructField, error) {
+	name := path[strings.LastIndexByte(path, '.')+1:]
+	f, ok := dt.FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, name) })
+	if !ok || f.PkgPath != "" {
+		return f, fmt.Errorf("no exported field %q in %s", name, dt)
+	}
+	return f, nil
+}
+
+// requiredExt marks required fields. See Required.
+type requiredExt struct {
+	paths []string
+}
+
+// Required marks the destination fields at paths as required, like the
+// "required" tag option: planning fails if a field has no source
+// counterpart, and conversion fails with ErrRequired if its source is nil or
+// zero. paths follow the rules of Transform.
+func Required(paths ...string) any {
+	return requiredExt{paths: paths}
+}
+
+// whenExt marks a field predicate. See When.
+type whenExt struct {
+	path string
+	fn   any
+}
+
+// When makes the destination field at path conditional: it is only converted
+// (or computed) when pred holds, and is otherwise left untouched. pred is
+// evaluated before the field's converter and is one of
+//
+//	func(src S) bool
+//	func(src S, dst *D) bool
+//
+// where S (or *S) is the source struct and D the destination struct owning
+// the field. Fields are converted in declaration order, so dst holds the
+// converted values of the fields declared before this one. Several
+// predicates on one field must all hold. path follows the rules of Transform.
+func When(path string, pred any) any {
+	return whenExt{path: path, fn: pred}
+}
+
+func (r *localConverterRegistry) addWhen(e whenExt) error {
+	rv := reflect.ValueOf(e.fn)
+	rt := reflect.TypeOf(e.fn)
+	if rt == nil || rt.Kind() != reflect.Func || rt.IsVariadic() || rt.NumIn() < 1 || rt.NumIn() > 2 ||
+		rt.NumOut() != 1 || rt.Out(0).Kind() != reflect.Bool || rt.NumIn() == 2 && rt.In(1).Kind() != reflect.Pointer {
+		return fmt.Errorf("condition for %q must be func(S) bool or func(S, *D) bool, got %T", e.path, e.fn)
+	}
+	fc := fieldCond{src: rt.In(0)}
+	byPtr := fc.src.Kind() == reflect.Pointer
+	if byPtr {
+		fc.src = fc.src.Elem()
+	}
+	if rt.NumIn() == 2 {
+		fc.dst = rt.In(1).Elem()
+	}
+	fc.call = func(src, dst reflect.Value) bool {
+		if byPtr {
+			src = addressable(src).Addr()
+		}
+		args := []reflect.Value{src}
+		if fc.dst != nil {
+			args = append(args, dst.Addr())
+		}
+		return rv.Call(args)[0].Bool()
+	}
+	cfg := r.fieldFor(e.path)
+	cfg.conds = append(cfg.conds, fc)
+	return nil
+}
+
+// conditions validates the predicates registered for path against the
+// owning pair st -> dt.
+func (fc *fieldConfig) conditions(path string, st, dt reflect.Type) ([]condition, error) {
+	var out []condition
+	for _, c := range fc.conds {
+		if c.src != st {
+			return nil, fmt.Errorf("condition for %q must take source %s, got %s", path, st, c.src)
+		}
+		if c.dst != nil && c.dst != dt {
+			return nil, fmt.Errorf("condition for %q must take destination *%s, got *%s", path, dt, c.dst)
+		}
+		out = append(out, c.call)
+	}
+	return out, nil
+}
+
+// allowed reports whether every condition holds.
+func allowed(conds []condition, src, dst reflect.Value) bool {
+	for _, c := range conds {
+		if !c(src, dst) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *localConverterRegistry) addTransform(t transformExt) error {
+	key, call, err := parseConverter(t.fn)
+	if err != nil {
+		return fmt.Errorf("transform for %q: %w", t.path, err)
+	}
+	if key.src != key.dst {
+		return fmt.Errorf("transform for %q must convert a type to itself, got %T", t.path, t.fn)
+	}
+	fc := r.fieldFor(t.path)
+	fc.transforms = append(fc.transforms, fieldTransform{typ: key.src, call: call})
+	return nil
+}
+
+func (r *localConverterRegistry) fieldFor(path string) *fieldConfig {
+	if r.fields == nil {
+		r.fields = make(map[string]*fieldConfig)
+	}
+	key := normPath(path)
+	fc := r.fields[key]
+	if fc == nil {
+		fc = &fieldConfig{}
+		r.fields[key] = fc
+	}
+	return fc
+}
+
+// field returns the extensions registered for path, or nil.
+func (r *localConverterRegistry) field(path string) *fieldConfig {
+	if r == nil {
+		return nil
+	}
+	return r.fields[normPath(path)]
+}
+
+// fieldPaths returns the normalized paths with registered extensions.
+func (r *localConverterRegistry) fieldPaths() []string {
+	paths := make([]string, 0, len(r.fields))
+	for p := range r.fields {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// hasFieldsUnder reports whether any extension targets a field nested below
+// path; the empty path is the top-level destination.
+func (r *localConverterRegistry) hasFieldsUnder(path string) bool {
+	if r == nil || len(r.fields) == 0 {
+		return false
+	}
+	if path == "" {
+		return true
+	}
+	prefix := normPath(path) + "."
+	for p := range r.fields {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func normPath(path string) string {
+	return strings.ToLower(path)
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,126 @@
+package typeconv
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// namedLayouts maps the names accepted by format= to time layouts, so that
+// layouts containing commas can be used in tags.
+var namedLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+var byteEncodings = map[string]*base64.Encoding{
+	"base64":    base64.StdEncoding,
+	"base64url": base64.URLEncoding,
+}
+
+// formatConv builds the converter for a format= tag option: a time layout
+// (or layout name) between time.Time and string types, or base64/base64url
+// between []byte and string types. Pointers on either side are handled as
+// usual. Empty strings and zero times convert to each other.
+func formatConv(st, dt reflect.Type, format string) (leafConv, error) {
+	sb, db := st, dt
+	for sb.Kind() == reflect.Pointer {
+		sb = sb.Elem()
+	}
+	for db.Kind() == reflect.Pointer {
+		db = db.Elem()
+	}
+	var fn func(dst, src reflect.Value) error
+	enc, isEncoding := byteEncodings[format]
+	layout := format
+	if l, ok := namedLayouts[format]; ok {
+		layout = l
+	}
+	switch {
+	case isEncoding && isBytes(sb) && db.Kind() == reflect.String:
+		fn = func(dst, src reflect.Value) error {
+			dst.SetString(enc.EncodeToString(src.Bytes()))
+			return nil
+		}
+	case isEncoding && sb.Kind() == reflect.String && isBytes(db):
+		fn = func(dst, src reflect.Value) error {
+			b, err := enc.DecodeString(src.String())
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(b).Convert(dst.Type()))
+			return nil
+		}
+	case !isEncoding && sb == timeType && db.Kind() == reflect.String:
+		fn = func(dst, src reflect.Value) error {
+			if t := src.Interface().(time.Time); !t.IsZero() {
+				dst.SetString(t.Format(layout))
+			} else {
+				dst.SetString("")
+			}
+			return nil
+		}
+	case !isEncoding && sb.Kind() == reflect.String && db == timeType:
+		fn = func(dst, src reflect.Value) error {
+			if src.String() == "" {
+				dst.SetZero()
+				return nil
+			}
+			t, err := time.Parse(layout, src.String())
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		}
+	default:
+		return nil, fmt.Errorf("format %q does not apply to %s -> %s", format, st, dt)
+	}
+	return derefConv(fn), nil
+}
+
+// derefConv adapts fn, which converts between non-pointer values, into a
+// leaf converter: a nil source zeroes dst and nil destination pointers are
+// allocated.
+func derefConv(fn func(dst, src reflect.Value) error) leafConv {
+	return derefContextConv(func(_ context.Context, dst, src reflect.Value) error { return fn(dst, src) })
+}
+
+// derefContextConv is derefConv for converters that need the context.
+func derefContextConv(fn leafConv) leafConv {
+	return func(ctx context.Context, dst, src reflect.Value) error {
+		for src.Kind() == reflect.Pointer {
+			if src.IsNil() {
+				dst.SetZero()
+				return nil
+			}
+			src = src.Elem()
+		}
+		for dst.Kind() == reflect.Pointer {
+			if dst.IsNil() {
+				dst.Set(reflect.New(dst.Type().Elem()))
+			}
+			dst = dst.Elem()
+		}
+		return fn(ctx, dst, src)
+	}
+}
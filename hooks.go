@@ -0,0 +1,79 @@
+package typeconv
+
+import (
+	"context"
+	"reflect"
+)
+
+// ConvertFromer is implemented by destination types that know how to fill
+// themselves from a source value. src is the dereferenced source value.
+// Returning handled=false lets the built-in rules convert the value instead.
+type ConvertFromer interface {
+	ConvertFrom(src any) (handled bool, err error)
+}
+
+// ConvertToer is implemented by source types that know how to fill a
+// destination. dst is a non-nil pointer to the destination value.
+// Returning handled=false lets the built-in rules convert the value instead.
+type ConvertToer interface {
+	ConvertTo(dst any) (handled bool, err error)
+}
+
+// BeforeConverter is implemented by destination structs that want to run
+// code before their fields are mapped from src.
+type BeforeConverter interface {
+	BeforeConvert(src any) error
+}
+
+// AfterConverter is implemented by destination structs that want to run
+// code after their fields are mapped from src, e.g. to derive fields.
+type AfterConverter interface {
+	AfterConvert(src any) error
+}
+
+var (
+	convertFromerType   = reflect.TypeOf((*ConvertFromer)(nil)).Elem()
+	convertToerType     = reflect.TypeOf((*ConvertToer)(nil)).Elem()
+	beforeConverterType = reflect.TypeOf((*BeforeConverter)(nil)).Elem()
+	afterConverterType  = reflect.TypeOf((*AfterConverter)(nil)).Elem()
+)
+
+// implements reports whether t or *t implements iface, with t's pointers
+// resolved first.
+func implements(t, iface reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func hasConvertHooks(st, dt reflect.Type) bool {
+	return implements(dt, convertFromerType) || implements(st, convertToerType)
+}
+
+// hookConv consults ConvertFrom on the destination, then ConvertTo on the
+// source, and falls back to next when neither handles the value.
+func hookConv(st, dt reflect.Type, next leafConv) leafConv {
+	from := implements(dt, convertFromerType)
+	to := implements(st, convertToerType)
+	// next gets the dereferenced values, which every built-in rule accepts.
+	return derefContextConv(func(ctx context.Context, dv, sv reflect.Value) error {
+		if from {
+			handled, err := dv.Addr().Interface().(ConvertFromer).ConvertFrom(sv.Interface())
+			if err != nil || handled {
+				return err
+			}
+		}
+		if to {
+			recv := sv
+			if !recv.Type().Implements(convertToerType) {
+				recv = addressable(recv).Addr()
+			}
+			handled, err := recv.Interface().(ConvertToer).ConvertTo(dv.Addr().Interface())
+			if err != nil || handled {
+				return err
+			}
+		}
+		return next(ctx, dv, sv)
+	})
+}
@@ -0,0 +1,294 @@
+package typeconv
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// jsonKinds is a set of JSON value kinds.
+type jsonKinds uint8
+
+const (
+	jsonBool jsonKinds = 1 << iota
+	jsonNumber
+	jsonString
+	jsonArray
+	jsonObject
+)
+
+func (k jsonKinds) String() string {
+	var names []string
+	for i, n := range []string{"bool", "number", "string", "array", "object"} {
+		if k&(1<<i) != 0 {
+			names = append(names, n)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// jsonCompatible reports, without any data, whether the JSON fallback can
+// convert st into dt: whether encoding/json can marshal st and unmarshal
+// every value it produces into dt. Types with custom JSON or text methods
+// are trusted on the side where they are used. null is ignored, since it
+// decodes into anything. With codec.DisallowUnknownFields, struct sources
+// must not have fields their struct destinations lack.
+func jsonCompatible(st, dt reflect.Type, codec JSONCodec) error {
+	return jsonChecker{seen: map[convKey]bool{}, disallowUnknown: codec.DisallowUnknownFields}.check(st, dt)
+}
+
+type jsonChecker struct {
+	// seen terminates recursion through self-referencing types.
+	seen            map[convKey]bool
+	disallowUnknown bool
+}
+
+func (c jsonChecker) check(st, dt reflect.Type) error {
+	for st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	for dt.Kind() == reflect.Pointer {
+		dt = dt.Elem()
+	}
+	key := convKey{src: st, dst: dt}
+	if c.seen[key] {
+		return nil
+	}
+	c.seen[key] = true
+
+	produced, err := jsonEncodes(st)
+	if err != nil || produced == 0 {
+		return err
+	}
+	accepted, err := jsonDecodes(dt)
+	if err != nil || accepted == 0 {
+		return err
+	}
+	if bad := produced &^ accepted; bad != 0 {
+		return fmt.Errorf("json: %s encodes as %s, which %s cannot decode", st, bad, dt)
+	}
+	switch {
+	case produced == jsonArray:
+		return c.check(st.Elem(), dt.Elem())
+	case produced == jsonObject:
+		return c.checkObject(st, dt)
+	}
+	return nil
+}
+
+// checkObject checks the members of a struct or map source against the
+// struct or map destination that decodes them.
+func (c jsonChecker) checkObject(st, dt reflect.Type) error {
+	if st.Kind() == reflect.Map && dt.Kind() == reflect.Map {
+		return c.check(st.Elem(), dt.Elem())
+	}
+	if st.Kind() == reflect.Map {
+		for _, f := range jsonFields(dt) {
+			if err := c.checkField(st.Elem(), f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if dt.Kind() == reflect.Map {
+		for _, sf := range jsonFields(st) {
+			if sf.quoted {
+				continue
+			}
+			if err := c.check(sf.typ, dt.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	dfields := jsonFields(dt)
+	var unknown []string
+	for name, sf := range jsonFields(st) {
+		df, ok := dfields[name]
+		if !ok {
+			unknown = append(unknown, sf.name)
+			continue
+		}
+		if sf.quoted {
+			continue
+		}
+		if err := c.checkField(sf.typ, df); err != nil {
+			return err
+		}
+	}
+	if c.disallowUnknown && len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("json: %s has fields unknown to %s, which DisallowUnknownFields rejects: %s", st, dt, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func (c jsonChecker) checkField(st reflect.Type, df jsonField) error {
+	if df.quoted {
+		return nil
+	}
+	return c.check(st, df.typ)
+}
+
+// jsonEncodes returns the JSON kinds json.Marshal can produce for t, or 0
+// when they are unknown.
+func jsonEncodes(t reflect.Type) (jsonKinds, error) {
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return 0, nil
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return jsonString, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return jsonBool, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return jsonNumber, nil
+	case reflect.String:
+		return jsonString, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !implementsAny(t.Elem(), jsonMarshalerType, textMarshalerType) {
+			return jsonString, nil
+		}
+		return jsonArray, nil
+	case reflect.Array:
+		return jsonArray, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				return 0, fmt.Errorf("json: unsupported map key type %s", t.Key())
+			}
+		}
+		return jsonObject, nil
+	case reflect.Struct:
+		return jsonObject, nil
+	case reflect.Interface:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("json: unsupported type %s", t)
+}
+
+// jsonDecodes returns the JSON kinds json.Unmarshal accepts into t, or 0
+// when t accepts anything.
+func jsonDecodes(t reflect.Type) (jsonKinds, error) {
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return 0, nil
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return jsonString, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return jsonBool, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return jsonNumber, nil
+	case reflect.String:
+		return jsonString, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return jsonString | jsonArray, nil
+		}
+		return jsonArray, nil
+	case reflect.Array:
+		return jsonArray, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !reflect.PointerTo(t.Key()).Implements(textUnmarshalerType) {
+				return 0, fmt.Errorf("json: cannot decode map key type %s", t.Key())
+			}
+		}
+		return jsonObject, nil
+	case reflect.Struct:
+		return jsonObject, nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("json: cannot decode into non-empty interface %s", t)
+	}
+	return 0, fmt.Errorf("json: cannot decode into %s", t)
+}
+
+func implementsAny(t reflect.Type, ifaces ...reflect.Type) bool {
+	for _, i := range ifaces {
+		if t.Implements(i) || reflect.PointerTo(t).Implements(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonField is a struct field as encoding/json sees it.
+type jsonField struct {
+	// name is the member name as encoded.
+	name string
+	typ  reflect.Type
+	// quoted marks the ",string" option, which changes the field's encoding.
+	quoted bool
+}
+
+// jsonFields returns the JSON members of struct type t by lower-cased name,
+// with untagged embedded structs flattened. Name conflicts are resolved in
+// favour of the shallower field, which is close enough for checking.
+func jsonFields(t reflect.Type) map[string]jsonField {
+	out := map[string]jsonField{}
+	var walk func(rt reflect.Type, depth int, visited map[reflect.Type]bool)
+	depths := map[string]int{}
+	walk = func(rt reflect.Type, depth int, visited map[reflect.Type]bool) {
+		if visited[rt] {
+			return
+		}
+		visited[rt] = true
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			tv := f.Tag.Get("json")
+			if tv == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tv, ",")
+			ft := f.Type
+			if f.Anonymous && name == "" {
+				for ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, depth+1, visited)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			key := strings.ToLower(name)
+			if d, ok := depths[key]; ok && d <= depth {
+				continue
+			}
+			depths[key] = depth
+			out[key] = jsonField{name: name, typ: f.Type, quoted: strings.Contains(","+opts+",", ",string,")}
+		}
+	}
+	walk(t, 0, map[reflect.Type]bool{})
+	return out
+}
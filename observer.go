@@ -0,0 +1,188 @@
+package typeconv
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Observer receives plan and conversion events, for metrics and tracing.
+// Set it in Options; plans without an observer are not instrumented at all.
+// Observe is called synchronously, possibly from many goroutines at once.
+type Observer interface {
+	Observe(ctx context.Context, e Event)
+}
+
+// ObserverFunc adapts a function to Observer.
+type ObserverFunc func(ctx context.Context, e Event)
+
+func (f ObserverFunc) Observe(ctx context.Context, e Event) { f(ctx, e) }
+
+// EventKind identifies what an Event reports.
+type EventKind uint8
+
+const (
+	// EventPlan reports a plan lookup by BuildPlan, Convert and their
+	// Converter variants, whether the plan was built or found in the cache.
+	EventPlan EventKind = iota + 1
+	// EventConvertStart and EventConvertEnd surround a conversion by a plan.
+	EventConvertStart
+	EventConvertEnd
+	// EventFallback reports a field converted by the fallback codec.
+	EventFallback
+	// EventCustom reports a field converted by a custom or named converter.
+	EventCustom
+)
+
+var eventKindNames = []string{"", "plan", "convert-start", "convert-end", "fallback", "custom"}
+
+func (k EventKind) String() string {
+	if int(k) < len(eventKindNames) && k != 0 {
+		return eventKindNames[k]
+	}
+	return "unknown"
+}
+
+// Event describes one observed step. Source and Destination are the types
+// of the plan for EventPlan and conversions, and of the field otherwise.
+type Event struct {
+	Kind                EventKind
+	Source, Destination reflect.Type
+	// Path is the destination field path of EventFallback and EventCustom,
+	// as in FieldError, e.g. "Items[2].Price".
+	Path string
+	// Cached reports an EventPlan found in the cache, or built by another
+	// goroutine at the same time.
+	Cached bool
+	// Duration is the time taken by the build, conversion or converter; it
+	// is zero for EventConvertStart and cached plans.
+	Duration time.Duration
+	// Err is the error of the build, conversion or converter, if any.
+	Err error
+}
+
+// observerKey carries the *observation of a running conversion in its
+// context, for the converters instrumented at plan time.
+type observerKey struct{}
+
+// observation is the state of an observed conversion: its observer, and the
+// destination path being converted, which observed plans push and pop as
+// they descend. A conversion runs on one goroutine, so it is not locked.
+type observation struct {
+	o    Observer
+	path []pathSegment
+}
+
+// pathSegment is a struct field name, or a slice index or map key if key
+// is valid or index is not negative.
+type pathSegment struct {
+	field string
+	index int
+	key   reflect.Value
+}
+
+// observing returns the observation of ctx, or nil.
+func observing(ctx context.Context) *observation {
+	ob, _ := ctx.Value(observerKey{}).(*observation)
+	return ob
+}
+
+// push appends seg to the path; a nil o ignores it, so that unobserved
+// conversions need no checks.
+func (o *observation) push(seg pathSegment) {
+	if o != nil {
+		o.path = append(o.path, seg)
+	}
+}
+
+func (o *observation) pop() {
+	if o != nil {
+		o.path = o.path[:len(o.path)-1]
+	}
+}
+
+// pathString renders the path as withSegment renders FieldError paths.
+func (o *observation) pathString() string {
+	var b strings.Builder
+	for _, seg := range o.path {
+		switch {
+		case seg.key.IsValid():
+			fmt.Fprintf(&b, "[%v]", seg.key.Interface())
+		case seg.index >= 0:
+			b.WriteString("[" + strconv.Itoa(seg.index) + "]")
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(seg.field)
+		}
+	}
+	return b.String()
+}
+
+func fieldSegment(name string) pathSegment { return pathSegment{field: name, index: -1} }
+func indexSegment(i int) pathSegment       { return pathSegment{index: i} }
+func keySegment(k reflect.Value) pathSegment {
+	return pathSegment{index: -1, key: k}
+}
+
+// observePlan reports the lookup of the plan for st -> dt to o, if set.
+func observePlan(o Observer, st, dt reflect.Type, cached bool, d time.Duration, err error) {
+	if o != nil {
+		o.Observe(context.Background(), Event{Kind: EventPlan, Source: st, Destination: dt, Cached: cached, Duration: d, Err: err})
+	}
+}
+
+// observedConv wraps conv, the kind converter for st -> dt, to report every
+// call to the observer of the conversion, at the path being converted.
+func observedConv(kind EventKind, st, dt reflect.Type, conv leafConv) leafConv {
+	return func(ctx context.Context, dst, src reflect.Value) error {
+		ob := observing(ctx)
+		if ob == nil {
+			return conv(ctx, dst, src)
+		}
+		path := ob.pathString()
+		start := time.Now()
+		err := conv(ctx, dst, src)
+		ob.o.Observe(ctx, Event{Kind: kind, Source: st, Destination: dt, Path: path, Duration: time.Since(start), Err: err})
+		return err
+	}
+}
+
+// SlogObserver returns an Observer logging every event as a debug record
+// to logger, e.g. to find hot fallback conversions without a profiler.
+func SlogObserver(logger *slog.Logger) Observer {
+	return ObserverFunc(func(ctx context.Context, e Event) {
+		if !logger.Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("src", typeName(e.Source)),
+			slog.String("dst", typeName(e.Destination)),
+		}
+		if e.Path != "" {
+			attrs = append(attrs, slog.String("path", e.Path))
+		}
+		if e.Kind == EventPlan {
+			attrs = append(attrs, slog.Bool("cached", e.Cached))
+		}
+		if e.Kind != EventConvertStart {
+			attrs = append(attrs, slog.Duration("duration", e.Duration))
+		}
+		if e.Err != nil {
+			attrs = append(attrs, slog.Any("error", e.Err))
+		}
+		logger.LogAttrs(ctx, slog.LevelDebug, "typeconv "+e.Kind.String(), attrs...)
+	})
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
@@ -1,25 +1,159 @@
 package typeconv
 
 import (
+	"encoding/binary"
+	"math"
 	"reflect"
 	"sync"
+	"unsafe"
 )
 
 type pair struct {
-	st, dt reflect.Type
-	strict bool
-	tag    string
+	st, dt     reflect.Type
+	policy     Policy
+	tag        string
+	defaultTag string
+	codec      Codec
+	// observed plans instrument fallback and custom converters.
+	observed   bool
+	unsafeCopy bool
+	// extensions is the extensionKey of the plan's extensions.
+	extensions string
 }
 
-var planCache sync.Map // map[pair]*Plan[_,_]
+// planCache holds *Plan[S, D] values.
+type planCache = lruCache[pair, any]
 
-func loadPlan[S any, D any](k pair) *Plan[S, D] {
-	if v, ok := planCache.Load(k); ok {
-		if p, ok2 := v.(*Plan[S, D]); ok2 {
-			return p
+// cachedPlan returns the plan for k in cache, building it with build on a
+// miss. Concurrent misses for the same pair share a single build.
+func cachedPlan[S any, D any](cache *planCache, k pair, build func() (*Plan[S, D], error)) (*Plan[S, D], error) {
+	p, err := cache.get(k, func() (any, error) { return build() })
+	if err != nil {
+		return nil, err
+	}
+	return p.(*Plan[S, D]), nil
+}
+
+// seenKeysLimit bounds the keys remembered by a seenKeys.
+const seenKeysLimit = 1024
+
+// seenKeys remembers the keys of plans built with per-call extensions. It
+// holds keys only, not plans, and forgets them all when full.
+type seenKeys struct {
+	mu   sync.Mutex
+	keys map[pair]struct{}
+}
+
+// seen reports whether k was seen since the last reset, and records it.
+func (s *seenKeys) seen(k pair) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[k]; ok {
+		return true
+	}
+	if s.keys == nil || len(s.keys) >= seenKeysLimit {
+		s.keys = make(map[pair]struct{})
+	}
+	s.keys[k] = struct{}{}
+	return false
+}
+
+func (s *seenKeys) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = nil
+}
+
+// extensionKey fingerprints extensions for the plan cache key. Functions
+// are identified by closure, so a closure created anew for every call misses
+// the cache while a converter kept in a variable hits it; maps, channels and
+// pointers are identified by address and everything else by value. The plan
+// cached under the key keeps the extensions alive, so that their addresses
+// cannot be reused by other values while it is cached.
+func extensionKey(extensions []any) string {
+	if len(extensions) == 0 {
+		return ""
+	}
+	var scratch [128]byte
+	w := keyWriter{buf: scratch[:0]}
+	for _, e := range extensions {
+		if t := reflect.TypeOf(e); t != nil && t.Kind() == reflect.Func {
+			// The common case, without copying: the data word of an
+			// interface holding a function is the function value itself.
+			w.word(uint64(reflect.ValueOf(t).Pointer()))
+			w.word(uint64(uintptr((*[2]unsafe.Pointer)(unsafe.Pointer(&e))[1])))
+			continue
 		}
+		w.any(reflect.ValueOf(e))
 	}
-	return nil
+	return string(w.buf)
 }
 
-func savePlan[S any, D any](k pair, p *Plan[S, D]) { planCache.Store(k, p) }
+type keyWriter struct {
+	buf []byte
+}
+
+func (w *keyWriter) word(x uint64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, x)
+}
+
+// any writes the dynamic type and value of v, which may be invalid for nil.
+func (w *keyWriter) any(v reflect.Value) {
+	if !v.IsValid() {
+		w.word(0)
+		return
+	}
+	w.word(uint64(reflect.ValueOf(v.Type()).Pointer()))
+	// An addressable copy gives access to the words of functions and of
+	// unexported fields.
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	w.value(c)
+}
+
+// value writes v, which must be addressable.
+func (w *keyWriter) value(v reflect.Value) {
+	// Values read from unexported fields cannot be copied; rebuilding v from
+	// its address lifts that restriction.
+	v = reflect.NewAt(v.Type(), v.Addr().UnsafePointer()).Elem()
+	switch v.Kind() {
+	case reflect.Func:
+		// The function value is a pointer to its closure.
+		w.word(uint64(uintptr(*(*unsafe.Pointer)(v.Addr().UnsafePointer()))))
+	case reflect.Pointer, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+		w.word(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			w.word(0)
+			return
+		}
+		w.any(v.Elem())
+	case reflect.String:
+		w.word(uint64(v.Len()))
+		w.buf = append(w.buf, v.String()...)
+	case reflect.Slice, reflect.Array:
+		w.word(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			w.value(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			w.value(v.Field(i))
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			w.word(1)
+		} else {
+			w.word(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.word(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.word(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		w.word((math.Float64bits(v.Float())))
+	case reflect.Complex64, reflect.Complex128:
+		w.word((math.Float64bits(real(v.Complex()))))
+		w.word((math.Float64bits(imag(v.Complex()))))
+	}
+}
@@ -0,0 +1,202 @@
+package typeconv
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy selects the families of built-in rules a plan may use for leaf
+// conversions. Struct, slice and map recursion, registered converters and
+// conversion hooks are always allowed. A field pair that needs a rule the
+// policy excludes fails planning.
+type Policy uint16
+
+const (
+	// AllowAssign permits identical and assignable types.
+	AllowAssign Policy = 1 << iota
+	// AllowConvert permits any reflect.Value.Convert, including lossy
+	// numeric conversions and int -> string.
+	AllowConvert
+	// AllowLosslessNumeric permits numeric conversions that cannot lose
+	// information, such as int32 -> int64 or uint16 -> float32.
+	AllowLosslessNumeric
+	// AllowText permits encoding.TextMarshaler types to string kinds and
+	// string kinds to encoding.TextUnmarshaler types.
+	AllowText
+	// AllowParse permits parsing string kinds into bools, numbers and
+	// time.Duration, and formatting those back, with strconv.
+	AllowParse
+	// AllowJSON permits the JSON round-trip fallback.
+	AllowJSON
+
+	// PolicyDefault is the policy of the zero Options: assignment,
+	// reflect.Convert and the JSON fallback.
+	PolicyDefault = AllowAssign | AllowConvert | AllowLosslessNumeric | AllowJSON
+	// PolicyStrict allows no conversion that can lose information or
+	// reinterpret data: only assignment and lossless numeric widening.
+	PolicyStrict = AllowAssign | AllowLosslessNumeric
+)
+
+var policyNames = []string{"assign", "convert", "lossless-numeric", "text", "parse", "json"}
+
+func (p Policy) String() string {
+	var names []string
+	for i, n := range policyNames {
+		if p&(1<<i) != 0 {
+			names = append(names, n)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// policy returns the effective policy of opts.
+func (o Options) policy() Policy {
+	p := o.Policy
+	if p == 0 {
+		p = PolicyDefault
+	}
+	if o.StrictTypes {
+		p &^= AllowConvert
+	}
+	return p
+}
+
+// errPolicy reports a pair that only a rule outside the policy could convert.
+func errPolicy(st, dt reflect.Type, p Policy, needs Policy) error {
+	return fmt.Errorf("no conversion rule for %s -> %s allowed by policy %s (needs %s)", st, dt, p, needs)
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// losslessNumeric reports whether every value of the numeric type st is
+// exactly representable in the numeric type dt.
+func losslessNumeric(st, dt reflect.Type) bool {
+	const (
+		signed = iota + 1
+		unsigned
+		float
+	)
+	class := func(k reflect.Kind) int {
+		switch k {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return signed
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return unsigned
+		case reflect.Float32, reflect.Float64:
+			return float
+		}
+		return 0
+	}
+	sc, dc := class(st.Kind()), class(dt.Kind())
+	if sc == 0 || dc == 0 {
+		return false
+	}
+	sb, db := st.Bits(), dt.Bits()
+	switch {
+	case sc == dc:
+		return sb <= db
+	case sc == unsigned && dc == signed:
+		return sb < db
+	case sc != float && dc == float:
+		// float32 and float64 hold 24 and 53 bit integers exactly.
+		mantissa := 24
+		if db == 64 {
+			mantissa = 53
+		}
+		return sb <= mantissa
+	}
+	return false
+}
+
+// textConv converts TextMarshaler types to string kinds and string kinds
+// to TextUnmarshaler types, or returns nil.
+func textConv(st, dt reflect.Type) leafConv {
+	sb, db := deref(st), deref(dt)
+	switch {
+	case db.Kind() == reflect.String && implements(sb, textMarshalerType):
+		return derefConv(func(dst, src reflect.Value) error {
+			recv := src
+			if !recv.Type().Implements(textMarshalerType) {
+				recv = addressable(recv).Addr()
+			}
+			b, err := recv.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return err
+			}
+			dst.SetString(string(b))
+			return nil
+		})
+	case sb.Kind() == reflect.String && reflect.PointerTo(db).Implements(textUnmarshalerType):
+		return derefConv(func(dst, src reflect.Value) error {
+			return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(src.String()))
+		})
+	}
+	return nil
+}
+
+// parsable reports whether t is a bool, number or time.Duration that the
+// parse rule handles.
+func parsable(t reflect.Type) bool {
+	if t == durationType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+	}
+	return false
+}
+
+// parseConv parses string kinds into parsable types and formats parsable
+// types as strings, or returns nil.
+func parseConv(st, dt reflect.Type) leafConv {
+	sb, db := deref(st), deref(dt)
+	switch {
+	case sb.Kind() == reflect.String && parsable(db):
+		return derefConv(func(dst, src reflect.Value) error {
+			v, err := parseText(src.String(), db)
+			if err != nil {
+				return err
+			}
+			dst.Set(v)
+			return nil
+		})
+	case db.Kind() == reflect.String && parsable(sb):
+		return derefConv(func(dst, src reflect.Value) error {
+			dst.SetString(formatText(src))
+			return nil
+		})
+	}
+	return nil
+}
+
+// formatText formats a parsable value so that parseText reads it back.
+func formatText(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	default:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	}
+}
@@ -0,0 +1,413 @@
+package typeconv
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+type convKey struct {
+	src reflect.Type
+	dst reflect.Type
+}
+
+// kindConverter marks a converter that applies to every source type sharing
+// the underlying type of its source parameter. See ByKind.
+type kindConverter struct {
+	fn any
+}
+
+// ByKind wraps a converter of any supported shape so that it matches any source
+// type whose underlying type is S (the ~S of a type constraint), not only S
+// itself. For example ByKind(func(src *int, dst *string) error) applies to
+// every named integer type based on int.
+func ByKind(fn any) any {
+	return kindConverter{fn: fn}
+}
+
+// namedConverter marks a converter registered under a name. See Named.
+type namedConverter struct {
+	name string
+	fn   any
+}
+
+// Named registers fn, a converter of any supported shape, under name so that
+// fields can select it with the conv=name option of the typeconv tag. Named
+// converters never match by type, so the same type pair can be converted
+// differently on different fields. A field whose tag names a converter that
+// is not registered, or whose types the converter does not accept, fails
+// planning.
+func Named(name string, fn any) any {
+	return namedConverter{name: name, fn: fn}
+}
+
+// ConverterFunc converts src into dst. Pointers are resolved before it is
+// called: src is never a pointer and dst is settable.
+type ConverterFunc func(src, dst reflect.Value) error
+
+// ConverterFactory builds converters for a whole family of types. It is
+// called once per (source, destination) pair when a plan is compiled, with
+// pointer types already resolved to their element types, and returns nil to
+// decline the pair. Factories are passed to Convert like any other converter,
+// so reusable modules can be expressed as a factory or a slice of them.
+type ConverterFactory func(src, dst reflect.Type) ConverterFunc
+
+// afterHook marks a function to run after automatic field mapping. See After.
+type afterHook struct {
+	fn any
+}
+
+// After wraps fn, a func(src *S, dst *D) error on struct types, into a hook
+// that runs after the fields of every S -> D pair have been mapped, whether
+// the pair is converted at the top level or nested in structs, slices or
+// maps. Use it to derive the few fields automatic mapping cannot fill.
+func After(fn any) any {
+	return afterHook{fn: fn}
+}
+
+// matcher is a registered converter that is selected by a predicate on the
+// source type rather than by exact type identity.
+type matcher struct {
+	key  convKey
+	conv leafConv
+}
+
+// localConverterRegistry is built per Convert call from user-provided functions.
+// Converters are selected by specificity: an exact (src, dst) pair wins over a
+// ByKind converter, which wins over a converter whose source is an interface.
+// Among matchers of equal specificity the first registered wins. Factories
+// are consulted last, in registration order.
+type localConverterRegistry struct {
+	exact     map[convKey]leafConv
+	kinds     []matcher
+	ifaces    []matcher
+	factories []ConverterFactory
+	afters    map[convKey][]converterCall
+	fields    map[string]*fieldConfig
+	named     map[string]matcher
+}
+
+// buildLocalRegistry validates and adapts user-provided converter functions into leaf converters.
+func buildLocalRegistry(custom []any) (*localConverterRegistry, error) {
+	reg := &localConverterRegistry{exact: make(map[convKey]leafConv)}
+	for _, c := range custom {
+		switch f := c.(type) {
+		case ConverterFactory:
+			reg.factories = append(reg.factories, f)
+			continue
+		case func(src, dst reflect.Type) ConverterFunc:
+			reg.factories = append(reg.factories, f)
+			continue
+		case []ConverterFactory:
+			reg.factories = append(reg.factories, f...)
+			continue
+		}
+		if e, ok := c.(requiredExt); ok {
+			for _, path := range e.paths {
+				reg.fieldFor(path).required = true
+			}
+			continue
+		}
+		if e, ok := c.(defaultExt); ok {
+			if err := reg.addDefault(e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if e, ok := c.(whenExt); ok {
+			if err := reg.addWhen(e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if e, ok := c.(computeExt); ok {
+			if err := reg.addCompute(e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if t, ok := c.(transformExt); ok {
+			if err := reg.addTransform(t); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if n, ok := c.(namedConverter); ok {
+			if err := reg.addNamed(n); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if h, ok := c.(afterHook); ok {
+			if err := reg.addAfter(h.fn); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		byKind := false
+		if kc, ok := c.(kindConverter); ok {
+			c, byKind = kc.fn, true
+		}
+		key, call, err := parseConverter(c)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case byKind:
+			if key.src.Kind() == reflect.Interface {
+				return nil, fmt.Errorf("ByKind converter source must not be an interface, got %T", c)
+			}
+			if err := reg.addMatcher(&reg.kinds, key, "kind", adaptConverter(call, key.src, func(v reflect.Value) reflect.Value {
+				return v.Convert(key.src)
+			})); err != nil {
+				return nil, err
+			}
+		case key.src.Kind() == reflect.Interface:
+			if err := reg.addMatcher(&reg.ifaces, key, "interface", adaptConverter(call, key.src, func(v reflect.Value) reflect.Value {
+				if !v.Type().Implements(key.src) {
+					v = addressable(v).Addr()
+				}
+				return v
+			})); err != nil {
+				return nil, err
+			}
+		default:
+			if _, exists := reg.exact[key]; exists {
+				return nil, fmt.Errorf("duplicate converter for %s -> %s", key.src.String(), key.dst.String())
+			}
+			reg.exact[key] = adaptConverter(call, key.src, nil)
+		}
+	}
+	return reg, nil
+}
+
+func (r *localConverterRegistry) addAfter(fn any) error {
+	rt := reflect.TypeOf(fn)
+	if rt == nil || rt.Kind() != reflect.Func || rt.NumIn() != 2 || rt.In(0).Kind() != reflect.Pointer || rt.In(1).Kind() != reflect.Pointer {
+		return fmt.Errorf("after hook must be func(*Src, *Dst) error, got %T", fn)
+	}
+	key, call, err := parseConverter(fn)
+	if err != nil {
+		return fmt.Errorf("after hook must be func(*Src, *Dst) error, got %T", fn)
+	}
+	if key.src.Kind() != reflect.Struct || key.dst.Kind() != reflect.Struct {
+		return fmt.Errorf("after hook must convert between structs, got %T", fn)
+	}
+	if r.afters == nil {
+		r.afters = make(map[convKey][]converterCall)
+	}
+	r.afters[key] = append(r.afters[key], call)
+	return nil
+}
+
+func (r *localConverterRegistry) addNamed(n namedConverter) error {
+	if n.name == "" || strings.ContainsAny(n.name, ",= ") {
+		return fmt.Errorf("invalid converter name %q", n.name)
+	}
+	if _, exists := r.named[n.name]; exists {
+		return fmt.Errorf("duplicate converter name %q", n.name)
+	}
+	key, call, err := parseConverter(n.fn)
+	if err != nil {
+		return fmt.Errorf("converter %q: %w", n.name, err)
+	}
+	var coerce func(reflect.Value) reflect.Value
+	if key.src.Kind() == reflect.Interface {
+		coerce = func(v reflect.Value) reflect.Value {
+			if !v.Type().Implements(key.src) {
+				v = addressable(v).Addr()
+			}
+			return v
+		}
+	}
+	if r.named == nil {
+		r.named = make(map[string]matcher)
+	}
+	r.named[n.name] = matcher{key: key, conv: adaptConverter(call, key.src, coerce)}
+	return nil
+}
+
+// namedConv returns the converter registered under name, checked against the
+// field types st -> dt.
+func (r *localConverterRegistry) namedConv(name string, st, dt reflect.Type) (leafConv, error) {
+	var m matcher
+	var ok bool
+	if r != nil {
+		m, ok = r.named[name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown converter %q", name)
+	}
+	for st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	for dt.Kind() == reflect.Pointer {
+		dt = dt.Elem()
+	}
+	srcOK := st == m.key.src ||
+		m.key.src.Kind() == reflect.Interface && (st.Implements(m.key.src) || reflect.PointerTo(st).Implements(m.key.src))
+	if !srcOK || dt != m.key.dst {
+		return nil, fmt.Errorf("converter %q converts %s -> %s, not %s -> %s", name, m.key.src, m.key.dst, st, dt)
+	}
+	return m.conv, nil
+}
+
+func (r *localConverterRegistry) addMatcher(list *[]matcher, key convKey, what string, conv leafConv) error {
+	for _, m := range *list {
+		if m.key == key {
+			return fmt.Errorf("duplicate %s converter for %s -> %s", what, key.src.String(), key.dst.String())
+		}
+	}
+	*list = append(*list, matcher{key: key, conv: conv})
+	return nil
+}
+
+// empty reports whether the registry holds no converters or hooks at all.
+func (r *localConverterRegistry) empty() bool {
+	return r == nil || len(r.exact) == 0 && len(r.kinds) == 0 && len(r.ifaces) == 0 && len(r.factories) == 0 &&
+		len(r.afters) == 0 && len(r.fields) == 0 && len(r.named) == 0
+}
+
+// afterHooks returns the after hooks registered for st -> dt.
+func (r *localConverterRegistry) afterHooks(st, dt reflect.Type) []converterCall {
+	if r == nil {
+		return nil
+	}
+	return r.afters[convKey{src: st, dst: dt}]
+}
+
+// converterCall invokes a user converter with src of the converter's source
+// type and an addressable dst of its destination type.
+type converterCall func(ctx context.Context, src, dst reflect.Value) error
+
+const converterShapes = "func(*Src, *Dst) error, func(Src) Dst, func(Src) (Dst, error) or func(context.Context, Src) (Dst, error)"
+
+// parseConverter validates the shape of a user converter function and
+// returns the type pair it converts and a uniform way to call it.
+func parseConverter(c any) (convKey, converterCall, error) {
+	rv := reflect.ValueOf(c)
+	if rv.Kind() != reflect.Func {
+		return convKey{}, nil, fmt.Errorf("custom converter must be func, got %T", c)
+	}
+	rt := rv.Type()
+	bad := func() (convKey, converterCall, error) {
+		return convKey{}, nil, fmt.Errorf("converter must be %s, got %s", converterShapes, rt.String())
+	}
+	if rt.IsVariadic() {
+		return bad()
+	}
+	switch {
+	// func(*Src, *Dst) error
+	case rt.NumIn() == 2 && rt.NumOut() == 1 && rt.In(0).Kind() == reflect.Pointer && rt.In(1).Kind() == reflect.Pointer &&
+		rt.Out(0).Implements(errorType):
+		key := convKey{src: rt.In(0).Elem(), dst: rt.In(1).Elem()}
+		return key, func(ctx context.Context, src, dst reflect.Value) error {
+			return errorResult(rv.Call([]reflect.Value{src.Addr(), dst.Addr()})[0])
+		}, nil
+	// func(Src) Dst and func(Src) (Dst, error)
+	case rt.NumIn() == 1 && (rt.NumOut() == 1 && rt.Out(0) != errorType || rt.NumOut() == 2 && rt.Out(1) == errorType):
+		key := convKey{src: rt.In(0), dst: rt.Out(0)}
+		if key.src.Kind() == reflect.Pointer || key.dst.Kind() == reflect.Pointer {
+			return convKey{}, nil, fmt.Errorf("value converter %s must use non-pointer types", rt.String())
+		}
+		return key, func(ctx context.Context, src, dst reflect.Value) error {
+			out := rv.Call([]reflect.Value{src})
+			if len(out) == 2 {
+				if err := errorResult(out[1]); err != nil {
+					return err
+				}
+			}
+			dst.Set(out[0])
+			return nil
+		}, nil
+	// func(context.Context, Src) (Dst, error)
+	case rt.NumIn() == 2 && rt.In(0) == contextType && rt.NumOut() == 2 && rt.Out(1) == errorType:
+		key := convKey{src: rt.In(1), dst: rt.Out(0)}
+		if key.src.Kind() == reflect.Pointer || key.dst.Kind() == reflect.Pointer {
+			return convKey{}, nil, fmt.Errorf("value converter %s must use non-pointer types", rt.String())
+		}
+		return key, func(ctx context.Context, src, dst reflect.Value) error {
+			out := rv.Call([]reflect.Value{reflect.ValueOf(&ctx).Elem(), src})
+			if err := errorResult(out[1]); err != nil {
+				return err
+			}
+			dst.Set(out[0])
+			return nil
+		}, nil
+	}
+	return bad()
+}
+
+func errorResult(v reflect.Value) error {
+	if e := v.Interface(); e != nil {
+		return e.(error)
+	}
+	return nil
+}
+
+// adaptConverter turns a parsed user converter into a leaf converter.
+// coerce, when set, maps the dereferenced source value onto Src.
+func adaptConverter(call converterCall, srcParam reflect.Type, coerce func(reflect.Value) reflect.Value) leafConv {
+	return derefContextConv(func(ctx context.Context, dstV, srcV reflect.Value) error {
+		if coerce != nil {
+			arg := reflect.New(srcParam)
+			arg.Elem().Set(coerce(srcV))
+			srcV = arg.Elem()
+		}
+		return call(ctx, addressable(srcV), dstV)
+	})
+}
+
+// adaptFactoryConv turns a factory-produced converter into a leaf converter.
+func adaptFactoryConv(fn ConverterFunc) leafConv {
+	return derefConv(func(dst, src reflect.Value) error { return fn(src, dst) })
+}
+
+// addressable returns v if it can be addressed, or an addressable copy of it.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// lookup returns the converter registered for st -> dt. Pointer types are
+// matched by their concrete element types, as converters are registered.
+func (r *localConverterRegistry) lookup(st, dt reflect.Type) (leafConv, bool) {
+	if r == nil {
+		return nil, false
+	}
+	for st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	for dt.Kind() == reflect.Pointer {
+		dt = dt.Elem()
+	}
+	if cv, ok := r.exact[convKey{src: st, dst: dt}]; ok {
+		return cv, true
+	}
+	for _, m := range r.kinds {
+		if m.key.dst == dt && st.Kind() == m.key.src.Kind() && st.ConvertibleTo(m.key.src) {
+			return m.conv, true
+		}
+	}
+	for _, m := range r.ifaces {
+		if m.key.dst == dt && (st.Implements(m.key.src) || reflect.PointerTo(st).Implements(m.key.src)) {
+			return m.conv, true
+		}
+	}
+	for _, f := range r.factories {
+		if fn := f(st, dt); fn != nil {
+			return adaptFactoryConv(fn), true
+		}
+	}
+	return nil, false
+}
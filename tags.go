@@ -0,0 +1,121 @@
+package typeconv
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// typeconvTag is the dedicated struct tag key. When present on a field it
+// takes precedence over Options.Tag.
+const typeconvTag = "typeconv"
+
+type tagDirection uint8
+
+const (
+	dirBoth  tagDirection = iota
+	dirRead               // only read when the struct is the source
+	dirWrite              // only written when the struct is the destination
+)
+
+// fieldTag is the parsed mapping tag of a struct field. The typeconv tag
+// has the form
+//
+//	typeconv:"name,opt,opt=value,...,default=value"
+//
+// with the options required, alias=name (repeatable), dir=read|write|both,
+// format=layout, conv=name and default=value. default= must come last and
+// takes the rest of the tag, so its value may contain commas.
+type fieldTag struct {
+	name     string
+	skip     bool
+	dir      tagDirection
+	aliases  []string
+	def      string
+	hasDef   bool
+	format   string
+	conv     string
+	required bool
+}
+
+// parseFieldTag parses the mapping tag of f: the typeconv tag if present,
+// otherwise the tag named by tag, whose unknown options are ignored since
+// other encoders share it. A typeconv tag without a name takes the name of
+// the tag named by tag.
+func parseFieldTag(f reflect.StructField, tag string) (fieldTag, error) {
+	if tv, ok := f.Tag.Lookup(typeconvTag); ok {
+		ft, err := parseTypeconvTag(tv)
+		if err != nil {
+			return ft, fmt.Errorf("malformed typeconv tag on field %s: %w", f.Name, err)
+		}
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); ft.name == "" && !ft.skip && name != "-" {
+			ft.name = name
+		}
+		return ft, nil
+	}
+	var ft fieldTag
+	tv, ok := f.Tag.Lookup(tag)
+	if !ok {
+		return ft, nil
+	}
+	if tv == "-" {
+		ft.skip = true
+		return ft, nil
+	}
+	parts := strings.Split(tv, ",")
+	ft.name = parts[0]
+	for _, opt := range parts[1:] {
+		if a, ok := strings.CutPrefix(opt, "alias="); ok && a != "" {
+			ft.aliases = append(ft.aliases, strings.ToLower(a))
+		}
+	}
+	ft.required = slices.Contains(parts[1:], "required")
+	return ft, nil
+}
+
+func parseTypeconvTag(tv string) (fieldTag, error) {
+	var ft fieldTag
+	if tv == "-" {
+		ft.skip = true
+		return ft, nil
+	}
+	if head, def, ok := strings.Cut(tv, ",default="); ok {
+		tv, ft.def, ft.hasDef = head, def, true
+	}
+	parts := strings.Split(tv, ",")
+	ft.name = parts[0]
+	if strings.ContainsAny(ft.name, "= ") {
+		return ft, fmt.Errorf("invalid name %q", ft.name)
+	}
+	for _, opt := range parts[1:] {
+		key, value, hasValue := strings.Cut(opt, "=")
+		if hasValue && value == "" {
+			return ft, fmt.Errorf("option %q needs a value", key)
+		}
+		switch {
+		case key == "required" && !hasValue:
+			ft.required = true
+		case key == "alias" && hasValue:
+			ft.aliases = append(ft.aliases, strings.ToLower(value))
+		case key == "dir" && hasValue:
+			switch value {
+			case "both":
+				ft.dir = dirBoth
+			case "read":
+				ft.dir = dirRead
+			case "write":
+				ft.dir = dirWrite
+			default:
+				return ft, fmt.Errorf("dir must be read, write or both, got %q", value)
+			}
+		case key == "format" && hasValue:
+			ft.format = value
+		case key == "conv" && hasValue:
+			ft.conv = value
+		default:
+			return ft, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return ft, nil
+}
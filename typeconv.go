@@ -1,99 +1,62 @@
 package typeconv
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
-	"sync"
+	"time"
 )
 
 var (
-	defaultOptions                   = Options{Tag: "json"}
+	defaultOptions                   = Options{Tag: "json", DefaultTag: "default"}
 	errNoOverlappingJSONTaggedFields = errors.New("no overlapping JSON-tagged fields")
 )
 
 type Options struct {
-	Tag         string
+	Tag string
+	// StrictTypes removes AllowConvert from the policy.
+	//
+	// Deprecated: set Policy, e.g. to PolicyStrict, instead.
 	StrictTypes bool
+	// Policy selects the built-in rule families plans may use; zero means
+	// PolicyDefault.
+	Policy Policy
+	// DefaultTag is the struct tag holding destination field defaults
+	// (default "default"); "-" disables default tags.
+	DefaultTag string
+	// Codec is the fallback codec (default JSONCodec{}).
+	Codec Codec
+	// Observer, if set, receives plan lookups, conversions and fallback and
+	// custom converter calls.
+	Observer Observer
+	// UnsafeCopy copies fields that plain assignment would convert, such as
+	// identical scalars and strings, directly in memory at offsets computed
+	// at plan time. Fields behind embedded pointers keep the regular path.
+	UnsafeCopy bool
 }
 
-type convKey struct {
-	src reflect.Type
-	dst reflect.Type
-}
-
-// localConverterRegistry is built per Convert call from user-provided functions.
-// It maps a pair of concrete (non-pointer) types to a leaf converter.
-type localConverterRegistry map[convKey]leafConv
-
 // Convert copies data from src to dst using a cached plan inferred from JSON tags.
 // Argument order: src, dst, [customConverters].
 func Convert[S any, D any](src *S, dst *D, customConverters ...any) error {
-	p, err := BuildPlan[S, D](defaultOptions)
-	if err != nil {
-		return err
-	}
-	reg, err := buildLocalRegistry(customConverters)
-	if err != nil {
-		return err
-	}
-	return p.convertWithRegistry(dst, src, reg)
+	return ConvertContext(context.Background(), src, dst, customConverters...)
 }
 
-// buildLocalRegistry validates and adapts user-provided converter functions into leaf converters.
-func buildLocalRegistry(custom []any) (localConverterRegistry, error) {
-	reg := make(localConverterRegistry)
-	for _, c := range custom {
-		rv := reflect.ValueOf(c)
-		if rv.Kind() != reflect.Func {
-			return nil, fmt.Errorf("custom converter must be func, got %T", c)
-		}
-		rt := rv.Type()
-		if rt.NumIn() != 2 || rt.In(0).Kind() != reflect.Pointer || rt.In(1).Kind() != reflect.Pointer {
-			return nil, fmt.Errorf("converter must be func(*Src, *Dst) error, got %s", rt.String())
-		}
-		if rt.NumOut() != 1 || !rt.Out(0).Implements(reflect.TypeOf((*error)(nil)).Elem()) {
-			return nil, fmt.Errorf("converter must return error, got %s", rt.String())
-		}
-		srcPtr := rt.In(0)
-		dstPtr := rt.In(1)
-		src := srcPtr.Elem()
-		dst := dstPtr.Elem()
-		key := convKey{src: src, dst: dst}
-		if _, exists := reg[key]; exists {
-			return nil, fmt.Errorf("duplicate converter for %s -> %s", src.String(), dst.String())
-		}
-		reg[key] = func(dstV, srcV reflect.Value) error {
-			for srcV.Kind() == reflect.Pointer {
-				if srcV.IsNil() {
-					dstV.SetZero()
-					return nil
-				}
-				srcV = srcV.Elem()
-			}
-			for dstV.Kind() == reflect.Pointer {
-				if dstV.IsNil() {
-					dstV.Set(reflect.New(dstV.Type().Elem()))
-				}
-				dstV = dstV.Elem()
-			}
-			args := []reflect.Value{srcV.Addr(), dstV.Addr()}
-			out := rv.Call(args)
-			if e := out[0].Interface(); e != nil {
-				return e.(error)
-			}
-			return nil
-		}
-	}
-	return reg, nil
+// ConvertContext is like Convert but honours cancellation of ctx between
+// slice and map elements and passes ctx to context-aware converters.
+func ConvertContext[S any, D any](ctx context.Context, src *S, dst *D, customConverters ...any) error {
+	return ConvertContextWith(ctx, defaultConverter, src, dst, customConverters...)
 }
 
 // Plan represents a compiled conversion plan between two types S and D.
 type Plan[S any, D any] struct {
-	steps []step
-	opts  Options
+	root *dynamicPlan
+	opts Options
+	// extensions keeps the extensions the plan was built with alive while it
+	// is cached; see extensionKey.
+	extensions []any
 }
 
 type step struct {
@@ -101,97 +64,182 @@ type step struct {
 	dstIndex []int
 	srcType  reflect.Type
 	dstType  reflect.Type
+	name     string
+	conv     leafConv
+	// transforms run in order on the destination after conv.
+	transforms []converterCall
+	// conds must all hold for the step to run.
+	conds []condition
+	// def, when set, replaces conversion of a zero source.
+	def func() reflect.Value
+	// required fails the step when the source is nil or zero.
+	required bool
+	// format and convName come from the typeconv tags of the two fields.
+	format   string
+	convName string
+	// rule records how conv was chosen, for Describe.
+	rule *leafRule
+	// copy, with Options.UnsafeCopy, replaces conv for addressable sources.
+	copy *fieldCopy
 }
 
-type leafConv func(dst, src reflect.Value) error
+type leafConv func(ctx context.Context, dst, src reflect.Value) error
 
 // BuildPlan creates a conversion plan between types S and D based on the provided options.
-func BuildPlan[S any, D any](opts Options) (*Plan[S, D], error) {
-	if opts.Tag == "" {
-		opts.Tag = "json"
+// Extensions accepts everything Convert accepts as custom converters, such as
+// converter functions, factories and After hooks. Plans are cached by type
+// pair, options and extensions, with functions identified by closure: pass
+// the same converter values to reuse the plan, e.g. by keeping them in
+// variables rather than creating closures on every call.
+func BuildPlan[S any, D any](opts Options, extensions ...any) (*Plan[S, D], error) {
+	return buildPlan[S, D](defaultConverter, opts, extensions)
+}
+
+// withDefaults fills in the defaults of unset options.
+func (o Options) withDefaults() Options {
+	if o.Tag == "" {
+		o.Tag = "json"
+	}
+	if o.DefaultTag == "" {
+		o.DefaultTag = "default"
 	}
+	if o.Codec == nil {
+		o.Codec = JSONCodec{}
+	}
+	return o
+}
+
+// buildPlan is BuildPlan using the cache and extensions of c, with opts and
+// the extra extensions of the call. Only the extra extensions are
+// fingerprinted on each call, and the registry is only built on a cache miss.
+func buildPlan[S any, D any](c *Converter, opts Options, extra []any) (*Plan[S, D], error) {
+	opts = opts.withDefaults()
 	st := reflect.TypeOf((*S)(nil)).Elem()
 	dt := reflect.TypeOf((*D)(nil)).Elem()
-	key := pair{st, dt, opts.StrictTypes, opts.Tag}
-	if p := loadPlan[S, D](key); p != nil {
+	cache := c.plans
+	codec, cacheable := codecKey(opts.Codec)
+	var key pair
+	if cacheable {
+		key = pair{st, dt, opts.policy(), opts.Tag, opts.DefaultTag, codec, opts.Observer != nil, opts.UnsafeCopy, c.extensionKey + extensionKey(extra)}
+		// Unobserved hits skip the build closure below.
+		if opts.Observer == nil {
+			if p, ok := cache.lookup(key); ok {
+				return p.(*Plan[S, D]), nil
+			}
+		}
+		// Plans of per-call extensions are only cached once their key comes
+		// back, so that closures created anew for every call do not fill
+		// the cache with single-use plans.
+		cacheable = len(extra) == 0 || c.seen.seen(key)
+	}
+	built := false
+	build := func() (*Plan[S, D], error) {
+		built = true
+		extensions := c.extensions
+		if len(extra) > 0 {
+			extensions = append(slices.Clip(extensions), extra...)
+		}
+		reg, err := buildLocalRegistry(extensions)
+		if err != nil {
+			return nil, err
+		}
+		p, err := compilePlan[S, D](st, dt, opts, reg)
+		if err != nil {
+			return nil, err
+		}
+		p.extensions = slices.Clone(extensions)
 		return p, nil
 	}
-
-	smap := getFieldMap(st, opts.Tag)
-	dmap := getFieldMap(dt, opts.Tag)
-	if len(smap) == 0 || len(dmap) == 0 {
-		return nil, fmt.Errorf("no mappable fields for tag %q", opts.Tag)
+	var start time.Time
+	if opts.Observer != nil {
+		start = time.Now()
+	}
+	var p *Plan[S, D]
+	var err error
+	if !cacheable {
+		p, err = build()
+	} else {
+		p, err = cachedPlan(cache, key, build)
 	}
+	if opts.Observer == nil {
+		return p, err
+	}
+	var d time.Duration
+	if built {
+		d = time.Since(start)
+	}
+	observePlan(opts.Observer, st, dt, !built, d, err)
+	if err == nil && !built {
+		// Cached plans are shared by every observer; report to this one.
+		p = &Plan[S, D]{root: p.root, opts: opts, extensions: p.extensions}
+	}
+	return p, err
+}
 
-	var steps []step
-	for name, sfi := range smap {
-		if dfi, ok := dmap[name]; ok {
-			stLeaf := st.FieldByIndex(sfi).Type
-			dtLeaf := dt.FieldByIndex(dfi).Type
-			steps = append(steps, step{srcIndex: sfi, dstIndex: dfi, srcType: stLeaf, dstType: dtLeaf})
+// compilePlan compiles the plan for st -> dt, the types of S and D.
+func compilePlan[S any, D any](st, dt reflect.Type, opts Options, reg *localConverterRegistry) (*Plan[S, D], error) {
+	sfields := getFields(st, opts.Tag)
+	dfields := getFields(dt, opts.Tag)
+	for _, fm := range []*fieldMap{sfields, dfields} {
+		if fm.err != nil {
+			return nil, fm.err
 		}
 	}
-	if len(steps) == 0 {
-		return nil, errNoOverlappingJSONTaggedFields
+	if len(sfields.index) == 0 || len(dfields.index) == 0 {
+		return nil, fmt.Errorf("no mappable fields for tag %q", opts.Tag)
+	}
+
+	root, err := newCompiler(opts, reg).root(st, dt)
+	if err != nil {
+		return nil, err
 	}
-	p := &Plan[S, D]{steps: steps, opts: opts}
-	savePlan(key, p)
-	return p, nil
+	return &Plan[S, D]{root: root, opts: opts}, nil
 }
 
 // Convert applies the conversion plan to copy data from src to dst.
 func (p *Plan[S, D]) Convert(dst *D, src *S) error {
-	if dst == nil || src == nil {
-		return errors.New("dst and src must be non-nil pointers")
-	}
-	dv := reflect.ValueOf(dst).Elem()
-	sv := reflect.ValueOf(src).Elem()
-	for _, s := range p.steps {
-		svLeaf := sv.FieldByIndex(s.srcIndex)
-		dvLeaf := dv.FieldByIndex(s.dstIndex)
-		if !dvLeaf.CanSet() {
-			return fmt.Errorf("destination field not settable at %v", s.dstIndex)
-		}
-		conv, err := makeLeafConv(s.srcType, s.dstType, p.opts, nil)
-		if err != nil {
-			return err
-		}
-		if err := conv(dvLeaf, svLeaf); err != nil {
-			return err
-		}
-	}
-	return nil
+	return p.convert(context.Background(), dst, src)
+}
+
+// ConvertContext is like Convert but honours cancellation of ctx between
+// slice and map elements and passes ctx to context-aware converters.
+func (p *Plan[S, D]) ConvertContext(ctx context.Context, dst *D, src *S) error {
+	return p.convert(ctx, dst, src)
 }
 
-// convertWithRegistry is like Convert but uses a provided local registry
-// of custom converters for this call.
-func (p *Plan[S, D]) convertWithRegistry(dst *D, src *S, reg localConverterRegistry) error {
+func (p *Plan[S, D]) convert(ctx context.Context, dst *D, src *S) error {
 	if dst == nil || src == nil {
 		return errors.New("dst and src must be non-nil pointers")
 	}
-	dv := reflect.ValueOf(dst).Elem()
-	sv := reflect.ValueOf(src).Elem()
-	for _, s := range p.steps {
-		svLeaf := sv.FieldByIndex(s.srcIndex)
-		dvLeaf := dv.FieldByIndex(s.dstIndex)
-		if !dvLeaf.CanSet() {
-			return fmt.Errorf("destination field not settable at %v", s.dstIndex)
-		}
-		conv, err := makeLeafConv(s.srcType, s.dstType, p.opts, reg)
-		if err != nil {
-			return err
-		}
-		if err := conv(dvLeaf, svLeaf); err != nil {
-			return err
-		}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o := p.opts.Observer
+	if o == nil {
+		return p.root.run(ctx, reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
 	}
-	return nil
+	ctx = context.WithValue(ctx, observerKey{}, &observation{o: o})
+	e := Event{Kind: EventConvertStart, Source: p.root.src, Destination: p.root.dst}
+	o.Observe(ctx, e)
+	start := time.Now()
+	e.Err = p.root.run(ctx, reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
+	e.Kind, e.Duration = EventConvertEnd, time.Since(start)
+	o.Observe(ctx, e)
+	return e.Err
 }
 
 // ---------------- Field discovery ----------------
-func buildFieldMap(t reflect.Type, tag string) map[string][]int {
-	m := map[string][]int{}
-	seen := map[string]bool{}
+
+// fieldMap indexes the mappable fields of a struct type by lower-cased name.
+type fieldMap struct {
+	index map[string][]int
+	tags  map[string]*fieldTag
+	// err records the first malformed tag; it fails planning.
+	err error
+}
+
+func buildFieldMap(t reflect.Type, tag string) *fieldMap {
+	fm := &fieldMap{index: map[string][]int{}, tags: map[string]*fieldTag{}}
 	var walk func(rt reflect.Type, path []int)
 	walk = func(rt reflect.Type, path []int) {
 		if rt.Kind() == reflect.Pointer {
@@ -205,11 +253,18 @@ func buildFieldMap(t reflect.Type, tag string) map[string][]int {
 			if f.PkgPath != "" {
 				continue
 			}
-			name, skip := tagName(f, tag)
-			if skip {
+			ft, err := parseFieldTag(f, tag)
+			if err != nil {
+				if fm.err == nil {
+					fm.err = fmt.Errorf("%s: %w", rt, err)
+				}
+				continue
+			}
+			if ft.skip {
 				continue
 			}
 			idx := append(append([]int{}, path...), i)
+			name := ft.name
 			if name == "" {
 				if f.Anonymous && isStructLike(f.Type) {
 					walk(f.Type, idx)
@@ -218,14 +273,14 @@ func buildFieldMap(t reflect.Type, tag string) map[string][]int {
 				name = f.Name
 			}
 			key := strings.ToLower(name)
-			if !seen[key] {
-				m[key] = idx
-				seen[key] = true
+			if _, seen := fm.index[key]; !seen {
+				fm.index[key] = idx
+				fm.tags[key] = &ft
 			}
 		}
 	}
 	walk(t, nil)
-	return m
+	return fm
 }
 
 // Cached field maps to avoid repeated reflection over struct fields
@@ -234,26 +289,22 @@ type fieldMapKey struct {
 	tag string
 }
 
-var fieldMapCache sync.Map // map[fieldMapKey]map[string][]int
+var fieldMapCache = newLRUCache[fieldMapKey, *fieldMap](DefaultFieldCacheLimit) // parsed tags included
 
-func getFieldMap(t reflect.Type, tag string) map[string][]int {
-	key := fieldMapKey{t: t, tag: tag}
-	if v, ok := fieldMapCache.Load(key); ok {
-		return v.(map[string][]int)
-	}
-	m := buildFieldMap(t, tag)
-	fieldMapCache.Store(key, m)
+func getFields(t reflect.Type, tag string) *fieldMap {
+	m, _ := fieldMapCache.get(fieldMapKey{t: t, tag: tag}, func() (*fieldMap, error) {
+		return buildFieldMap(t, tag), nil
+	})
 	return m
 }
 
-func tagName(f reflect.StructField, tag string) (name string, skip bool) {
-	if tv, ok := f.Tag.Lookup(tag); ok {
-		if tv == "-" {
-			return "", true
-		}
-		parts := strings.Split(tv, ",")
-		if parts[0] != "" {
-			return parts[0], false
+// sourceFor returns the source field feeding the destination field name:
+// the field of the same name, or else the first of its aliases present in
+// the source. Write-only source fields are never read.
+func (src *fieldMap) sourceFor(name string, dst *fieldMap) (string, bool) {
+	for _, cand := range append([]string{name}, dst.tags[name].aliases...) {
+		if ft, ok := src.tags[cand]; ok && ft.dir != dirWrite {
+			return cand, true
 		}
 	}
 	return "", false
@@ -267,72 +318,172 @@ func isStructLike(t reflect.Type) bool {
 }
 
 // ---------------- Converters ----------------
-func makeLeafConv(st, dt reflect.Type, opts Options, reg localConverterRegistry) (leafConv, error) {
-	// 1. Direct types
-	if dt == st || dt.AssignableTo(st) || st.AssignableTo(dt) {
-		return assignConv(st, dt), nil
+
+// leaf returns the converter for st -> dt at the destination path, compiling
+// it on first use. Slice elements and map values share their field's path.
+func (c *compiler) leaf(st, dt reflect.Type, path string) (leafConv, *leafRule, error) {
+	key := c.key(st, dt, path)
+	conv, ok := c.leaves[key]
+	if !ok {
+		var rule *leafRule
+		var err error
+		conv, rule, err = c.makeLeafConv(st, dt, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c.opts.Observer != nil {
+			switch rule.rule {
+			case RuleCustom:
+				conv = observedConv(EventCustom, st, dt, conv)
+			case RuleJSON:
+				conv = observedConv(EventFallback, st, dt, conv)
+			}
+		}
+		c.leaves[key] = conv
+		c.added = append(c.added, key)
+		c.rules[key] = rule
+	}
+	return conv, c.rules[key], nil
+}
+
+func (c *compiler) makeLeafConv(st, dt reflect.Type, path string) (leafConv, *leafRule, error) {
+	// 1. Per-call custom converter; takes precedence over every built-in rule
+	// so that identical-type normalizers (e.g. string -> string) are reachable.
+	if cv := c.custom(st, dt); cv != nil {
+		return cv, &leafRule{rule: RuleCustom}, nil
 	}
 
-	// 2. Per-call custom converter
-	if reg != nil {
-		if cv, ok := reg[convKey{st, dt}]; ok {
-			return cv, nil
+	// 2. Conversion hooks implemented by the types themselves; when a hook
+	// declines a value the built-in rules below handle it.
+	if hasConvertHooks(st, dt) {
+		invalid, added := len(c.invalid), len(c.added)
+		next, nextRule, err := c.builtinConv(st, dt, path)
+		if err != nil || len(c.invalid) > invalid {
+			// The hooks may handle every value; fail only those they decline,
+			// with the errors of the fallback, and forget its partial plans.
+			if errs := c.rollback(invalid, added); err == nil {
+				err = &PlanError{Errs: errs}
+			}
+			next = func(ctx context.Context, dst, src reflect.Value) error { return err }
+			nextRule = &leafRule{rule: RuleJSON}
+		}
+		return hookConv(st, dt, next), &leafRule{rule: RuleHook, next: nextRule}, nil
+	}
+	return c.builtinConv(st, dt, path)
+}
+
+// builtinConv selects the first built-in rule that applies to st -> dt and
+// that the policy allows.
+func (c *compiler) builtinConv(st, dt reflect.Type, path string) (leafConv, *leafRule, error) {
+	policy := c.opts.policy()
+
+	// 3. Direct types
+	if dt == st || dt.AssignableTo(st) || st.AssignableTo(dt) {
+		if policy&AllowAssign == 0 {
+			return nil, nil, errPolicy(st, dt, policy, AllowAssign)
 		}
+		return assignConv(st, dt), &leafRule{rule: RuleAssign}, nil
 	}
 
-	// 3. Struct recursion
+	// 4. Struct recursion
 	if isStructLike(st) && isStructLike(dt) {
-		return structConv(st, dt, opts, reg), nil
+		conv, rule := c.structConv(st, dt, path)
+		return conv, rule, nil
 	}
 
-	// 4. Slice
+	// 5. Slice
 	if st.Kind() == reflect.Slice && dt.Kind() == reflect.Slice {
-		elemConv, err := makeLeafConv(st.Elem(), dt.Elem(), opts, reg)
+		elemConv, elemRule, err := c.leaf(st.Elem(), dt.Elem(), path)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		return sliceConv(elemConv, dt), nil
+		return sliceConv(elemConv, dt, c.opts.Observer != nil), &leafRule{rule: RuleSlice, elem: elemRule}, nil
 	}
 
-	// 5. Map[string]T
+	// 6. Map[string]T
 	if st.Kind() == reflect.Map && dt.Kind() == reflect.Map && st.Key().Kind() == reflect.String && dt.Key().Kind() == reflect.String {
-		elemConv, err := makeLeafConv(st.Elem(), dt.Elem(), opts, reg)
+		elemConv, elemRule, err := c.leaf(st.Elem(), dt.Elem(), path)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		return mapConv(elemConv, dt, c.opts.Observer != nil), &leafRule{rule: RuleMap, elem: elemRule}, nil
+	}
+
+	// 7. Lossless numeric widening
+	if policy&AllowLosslessNumeric != 0 && losslessNumeric(deref(st), deref(dt)) {
+		return assignConv(st, dt), &leafRule{rule: RuleConvert}, nil
+	}
+
+	// 8. Text marshaling and string parsing
+	if policy&AllowText != 0 {
+		if conv := textConv(st, dt); conv != nil {
+			return conv, &leafRule{rule: RuleText}, nil
+		}
+	}
+	if policy&AllowParse != 0 {
+		if conv := parseConv(st, dt); conv != nil {
+			return conv, &leafRule{rule: RuleParse}, nil
 		}
-		return mapConv(elemConv, dt), nil
 	}
 
-	// 6. Convertible
-	if !opts.StrictTypes && st.ConvertibleTo(dt) {
-		return assignConv(st, dt), nil
+	// 9. Convertible
+	if policy&AllowConvert != 0 && st.ConvertibleTo(dt) {
+		return assignConv(st, dt), &leafRule{rule: RuleConvert}, nil
 	}
 
-	// 7. JSON fallback
-	return jsonFallbackConv(st, dt)
+	// 10. JSON fallback, if the types can round-trip through JSON at all
+	return c.jsonConv(st, dt, policy)
 }
 
-func assignConv(st, dt reflect.Type) leafConv {
-	return func(dst, src reflect.Value) error {
-		for src.Kind() == reflect.Pointer {
-			if src.IsNil() {
-				dst.SetZero()
-				return nil
-			}
-			src = src.Elem()
+// jsonConv returns the JSON fallback for st -> dt if the policy allows it and
+// the types can round-trip through JSON.
+func (c *compiler) jsonConv(st, dt reflect.Type, policy Policy) (leafConv, *leafRule, error) {
+	if policy&AllowJSON == 0 {
+		needs := AllowJSON
+		switch {
+		case textConv(st, dt) != nil:
+			needs = AllowText
+		case parseConv(st, dt) != nil:
+			needs = AllowParse
+		case st.ConvertibleTo(dt):
+			needs = AllowConvert
+		}
+		return nil, nil, errPolicy(st, dt, policy, needs)
+	}
+	// Only JSON can be checked without data.
+	if codec, ok := c.opts.Codec.(JSONCodec); ok {
+		if err := jsonCompatible(st, dt, codec); err != nil {
+			return nil, nil, fmt.Errorf("no conversion rule for %s -> %s: %w", st, dt, err)
 		}
-		for dst.Kind() == reflect.Pointer {
-			if dst.IsNil() {
-				dst.Set(reflect.New(dst.Type().Elem()))
-			}
-			dst = dst.Elem()
+	}
+	conv := jsonFallbackConv(c.opts.Codec, st, dt)
+	if sk := deref(st).Kind(); sk == reflect.Map || sk == reflect.Interface {
+		if aliases := aliasesFor(dt, c.opts.Tag); aliases != nil {
+			conv = aliasConv(aliases, conv)
 		}
+	}
+	return conv, &leafRule{rule: RuleJSON}, nil
+}
+
+// aliasConv applies the field aliases of the destination to map sources
+// before conv decodes them.
+func aliasConv(aliases *aliasNode, conv leafConv) leafConv {
+	return func(ctx context.Context, dst, src reflect.Value) error {
+		for src.Kind() == reflect.Pointer && !src.IsNil() {
+			src = src.Elem()
+		}
+		return conv(ctx, dst, aliases.apply(src))
+	}
+}
+
+func assignConv(st, dt reflect.Type) leafConv {
+	return derefConv(func(dst, src reflect.Value) error {
 		dst.Set(src.Convert(dst.Type()))
 		return nil
-	}
+	})
 }
 
-func structConv(st, dt reflect.Type, opts Options, reg localConverterRegistry) leafConv {
+func (c *compiler) structConv(st, dt reflect.Type, path string) (leafConv, *leafRule) {
 	// Normalize to non-pointer struct types for planning
 	stBase := st
 	if stBase.Kind() == reflect.Pointer {
@@ -344,45 +495,45 @@ func structConv(st, dt reflect.Type, opts Options, reg localConverterRegistry) l
 	}
 
 	// Build once at converter creation time
-	dp, err := buildDynamicPlan(stBase, dtBase, opts)
+	dp, err := c.plan(stBase, dtBase, path)
 	if err != nil {
 		if errors.Is(err, errNoOverlappingJSONTaggedFields) {
-			jsonConv, _ := jsonFallbackConv(st, dt)
-			return func(dst, src reflect.Value) error {
-				return jsonConv(dst, src)
+			jsonConv, rule, err := c.jsonConv(st, dt, c.opts.policy())
+			if err != nil {
+				c.invalid = append(c.invalid, err)
+				return func(ctx context.Context, dst, src reflect.Value) error { return err }, &leafRule{rule: RuleJSON}
 			}
+			return jsonConv, rule
 		}
-		return func(dst, src reflect.Value) error { return err }
-	}
-	return func(dst, src reflect.Value) error {
-		for src.Kind() == reflect.Pointer {
-			if src.IsNil() {
-				dst.SetZero()
-				return nil
-			}
-			src = src.Elem()
-		}
-		for dst.Kind() == reflect.Pointer {
-			if dst.IsNil() {
-				dst.Set(reflect.New(dst.Type().Elem()))
-			}
-			dst = dst.Elem()
-		}
-		return dp.run(dst, src, reg)
+		c.invalid = append(c.invalid, err)
+		return func(ctx context.Context, dst, src reflect.Value) error { return err }, &leafRule{rule: RuleStruct}
 	}
+	return derefContextConv(dp.run), &leafRule{rule: RuleStruct, plan: dp}
 }
 
-func sliceConv(elemConv leafConv, dt reflect.Type) leafConv {
-	return func(dst, src reflect.Value) error {
+// sliceConv converts slices element by element; observed converters also
+// track the element index in the path of the observation.
+func sliceConv(elemConv leafConv, dt reflect.Type, observed bool) leafConv {
+	return func(ctx context.Context, dst, src reflect.Value) error {
 		if src.IsNil() {
 			dst.SetZero()
 			return nil
 		}
+		var ob *observation
+		if observed {
+			ob = observing(ctx)
+		}
 		ln := src.Len()
 		out := reflect.MakeSlice(dt, ln, ln)
 		for i := 0; i < ln; i++ {
-			if err := elemConv(out.Index(i), src.Index(i)); err != nil {
-				return err
+			if err := ctx.Err(); err != nil {
+				return withIndex(i, err)
+			}
+			ob.push(indexSegment(i))
+			err := elemConv(ctx, out.Index(i), src.Index(i))
+			ob.pop()
+			if err != nil {
+				return withIndex(i, err)
 			}
 		}
 		dst.Set(out)
@@ -390,18 +541,30 @@ func sliceConv(elemConv leafConv, dt reflect.Type) leafConv {
 	}
 }
 
-func mapConv(elemConv leafConv, dt reflect.Type) leafConv {
-	return func(dst, src reflect.Value) error {
+// mapConv converts maps value by value; observed converters also track the
+// key in the path of the observation.
+func mapConv(elemConv leafConv, dt reflect.Type, observed bool) leafConv {
+	return func(ctx context.Context, dst, src reflect.Value) error {
 		if src.IsNil() {
 			dst.SetZero()
 			return nil
 		}
+		var ob *observation
+		if observed {
+			ob = observing(ctx)
+		}
 		out := reflect.MakeMapWithSize(dt, src.Len())
 		iter := src.MapRange()
 		for iter.Next() {
+			if err := ctx.Err(); err != nil {
+				return withKey(iter.Key(), err)
+			}
 			ov := reflect.New(dt.Elem()).Elem()
-			if err := elemConv(ov, iter.Value()); err != nil {
-				return err
+			ob.push(keySegment(iter.Key()))
+			err := elemConv(ctx, ov, iter.Value())
+			ob.pop()
+			if err != nil {
+				return withKey(iter.Key(), err)
 			}
 			out.SetMapIndex(iter.Key().Convert(dt.Key()), ov)
 		}
@@ -410,29 +573,24 @@ func mapConv(elemConv leafConv, dt reflect.Type) leafConv {
 	}
 }
 
-func jsonFallbackConv(st, dt reflect.Type) (leafConv, error) {
-	return func(dst, src reflect.Value) error {
-		for src.Kind() == reflect.Pointer {
-			if src.IsNil() {
-				dst.SetZero()
-				return nil
-			}
-			src = src.Elem()
-		}
-		for dst.Kind() == reflect.Pointer {
-			if dst.IsNil() {
-				dst.Set(reflect.New(dst.Type().Elem()))
-			}
-			dst = dst.Elem()
-		}
-		if src.IsZero() {
-			dst.SetZero()
+// jsonFallbackConv converts st to dt by round-tripping values through codec
+// into a zeroed destination. Zero sources skip the codec unless the zero
+// value round-trips to something other than the zero destination.
+func jsonFallbackConv(codec Codec, st, dt reflect.Type) leafConv {
+	tc, pooled := codec.(JSONCodec)
+	zeroToZero := zeroRoundTrips(codec, deref(st), deref(dt))
+	return derefConv(func(dst, src reflect.Value) error {
+		dst.SetZero()
+		if zeroToZero && src.IsZero() {
 			return nil
 		}
-		data, err := json.Marshal(src.Interface())
+		if pooled {
+			return tc.transcode(dst.Addr().Interface(), src.Interface())
+		}
+		data, err := codec.Marshal(src.Interface())
 		if err != nil {
 			return err
 		}
-		return json.Unmarshal(data, dst.Addr().Interface())
-	}, nil
+		return codec.Unmarshal(data, dst.Addr().Interface())
+	})
 }
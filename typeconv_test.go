@@ -1,10 +1,17 @@
 package typeconv
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -311,6 +318,1298 @@ func TestCustomConverterPrecedence(t *testing.T) {
 	assert.Equal(t, 99, d.X.V)
 }
 
+func TestCustomConverterOverridesDirectAssign(t *testing.T) {
+	trim := func(src *string, dst *string) error {
+		*dst = strings.TrimSpace(*src)
+		return nil
+	}
+
+	type S struct {
+		Name  string  `json:"name"`
+		Alias *string `json:"alias"`
+	}
+	type D struct {
+		Name  string  `json:"name"`
+		Alias *string `json:"alias"`
+	}
+
+	alias := "  bob "
+	s := S{Name: "  alice  ", Alias: &alias}
+	var d D
+	require.NoError(t, Convert(&s, &d, trim), "Convert failed")
+	assert.Equal(t, "alice", d.Name)
+	if assert.NotNil(t, d.Alias) {
+		assert.Equal(t, "bob", *d.Alias)
+	}
+}
+
+type colour int
+
+func (c colour) String() string { return [...]string{"red", "green"}[c] }
+
+type shape string
+
+func (s *shape) String() string { return strings.ToUpper(string(*s)) }
+
+func TestInterfaceAndKindConverters(t *testing.T) {
+	stringer := func(src *fmt.Stringer, dst *string) error {
+		*dst = (*src).String()
+		return nil
+	}
+	fromInt := ByKind(func(src *int, dst *string) error {
+		*dst = "#" + strconv.Itoa(*src)
+		return nil
+	})
+
+	type Level int
+	type S struct {
+		Colour colour `json:"colour"`
+		Shape  shape  `json:"shape"`
+		Level  Level  `json:"level"`
+	}
+	type D struct {
+		Colour string `json:"colour"`
+		Shape  string `json:"shape"`
+		Level  string `json:"level"`
+	}
+
+	s := S{Colour: 1, Shape: "square", Level: 3}
+	var d D
+	require.NoError(t, Convert(&s, &d, stringer, fromInt), "Convert failed")
+	// colour is both ~int and a Stringer: the ByKind converter is more specific.
+	assert.Equal(t, "#1", d.Colour)
+	assert.Equal(t, "SQUARE", d.Shape)
+	assert.Equal(t, "#3", d.Level)
+
+	exact := func(src *colour, dst *string) error {
+		*dst = "exact"
+		return nil
+	}
+	d = D{}
+	require.NoError(t, Convert(&s, &d, stringer, fromInt, exact), "Convert failed")
+	assert.Equal(t, "exact", d.Colour)
+
+	assert.Error(t, Convert(&s, &d, fromInt, fromInt), "expected duplicate error")
+}
+
+func TestConverterShapes(t *testing.T) {
+	type Celsius float64
+	type Fahrenheit float64
+	type Code string
+	type ID int
+
+	type S struct {
+		Temp Celsius `json:"temp"`
+		Code Code    `json:"code"`
+		ID   string  `json:"id"`
+	}
+	type D struct {
+		Temp Fahrenheit `json:"temp"`
+		Code string     `json:"code"`
+		ID   ID         `json:"id"`
+	}
+
+	toF := func(c Celsius) Fahrenheit { return Fahrenheit(c*9/5 + 32) }
+	code := func(c Code) (string, error) { return "C-" + string(c), nil }
+	id := func(ctx context.Context, s string) (ID, error) {
+		n, err := strconv.Atoi(s)
+		return ID(n), err
+	}
+
+	s := S{Temp: 100, Code: "x", ID: "7"}
+	var d D
+	require.NoError(t, Convert(&s, &d, toF, code, id), "Convert failed")
+	assert.Equal(t, Fahrenheit(212), d.Temp)
+	assert.Equal(t, "C-x", d.Code)
+	assert.Equal(t, ID(7), d.ID)
+
+	s.ID = "seven"
+	assert.Error(t, Convert(&s, &d, toF, code, id), "expected converter error")
+
+	err := Convert(&s, &d, func(a, b Code) error { return nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "func(Src) (Dst, error)")
+}
+
+func TestConverterFactory(t *testing.T) {
+	type Cents int64
+	type Euros int64
+	type Dollars float64
+
+	calls := 0
+	// A "money module": any integer-cents type converts to any float type.
+	money := ConverterFactory(func(src, dst reflect.Type) ConverterFunc {
+		if !strings.HasPrefix(src.Name(), "Cents") || dst.Kind() != reflect.Float64 {
+			return nil
+		}
+		calls++
+		return func(src, dst reflect.Value) error {
+			dst.SetFloat(float64(src.Int()) / 100)
+			return nil
+		}
+	})
+
+	type S struct {
+		Price  Cents   `json:"price"`
+		Totals []Cents `json:"totals"`
+		Fee    *Cents  `json:"fee"`
+		Tax    Euros   `json:"tax"`
+	}
+	type D struct {
+		Price  Dollars   `json:"price"`
+		Totals []Dollars `json:"totals"`
+		Fee    *Dollars  `json:"fee"`
+		Tax    Dollars   `json:"tax"`
+	}
+
+	fee := Cents(50)
+	s := S{Price: 1250, Totals: []Cents{100, 275}, Fee: &fee, Tax: 3}
+	var d D
+	require.NoError(t, Convert(&s, &d, money), "Convert failed")
+	assert.Equal(t, Dollars(12.5), d.Price)
+	assert.Equal(t, []Dollars{1, 2.75}, d.Totals)
+	if assert.NotNil(t, d.Fee) {
+		assert.Equal(t, Dollars(0.5), *d.Fee)
+	}
+	// Declined pairs fall through to the built-in rules.
+	assert.Equal(t, Dollars(3), d.Tax)
+	// Consulted once per type pair, not per field or element.
+	assert.Equal(t, 1, calls)
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	type NodeA struct {
+		V    int    `json:"v"`
+		Next *NodeA `json:"next"`
+	}
+	type NodeB struct {
+		V    int    `json:"v"`
+		Next *NodeB `json:"next"`
+	}
+
+	s := NodeA{V: 1, Next: &NodeA{V: 2, Next: &NodeA{V: 3}}}
+	var d NodeB
+	require.NoError(t, Convert(&s, &d), "Convert failed")
+	require.NotNil(t, d.Next)
+	require.NotNil(t, d.Next.Next)
+	assert.Equal(t, 3, d.Next.Next.V)
+	assert.Nil(t, d.Next.Next.Next)
+}
+
+type tenantKey struct{}
+
+func TestConvertContext(t *testing.T) {
+	type Code string
+	type IA struct {
+		Code Code `json:"code"`
+	}
+	type IB struct {
+		Code string `json:"code"`
+	}
+	type S struct {
+		Items []IA `json:"items"`
+	}
+	type D struct {
+		Items []IB `json:"items"`
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), tenantKey{}, "acme"))
+	defer cancel()
+	seen, cancelAt := 0, -1
+	conv := func(ctx context.Context, c Code) (string, error) {
+		seen++
+		if seen == cancelAt {
+			cancel()
+		}
+		return ctx.Value(tenantKey{}).(string) + "/" + string(c), nil
+	}
+
+	s := S{Items: []IA{{Code: "a"}, {Code: "b"}, {Code: "c"}}}
+	var d D
+	require.NoError(t, ConvertContext(context.WithValue(context.Background(), tenantKey{}, "acme"), &s, &d, conv))
+	assert.Equal(t, "acme/c", d.Items[2].Code)
+
+	seen, cancelAt = 0, 2
+	err := ConvertContext(ctx, &s, &d, conv)
+	require.ErrorIs(t, err, context.Canceled)
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "Items[2]", fe.Path)
+
+	p, err := BuildPlan[S, D](Options{})
+	require.NoError(t, err)
+	assert.ErrorIs(t, p.ConvertContext(ctx, &d, &s), context.Canceled)
+}
+
+func TestFieldErrorPath(t *testing.T) {
+	type Code string
+	type IA struct {
+		Code Code `json:"code"`
+	}
+	type IB struct {
+		Code int `json:"code"`
+	}
+	type S struct {
+		M map[string][]IA `json:"m"`
+	}
+	type D struct {
+		M map[string][]IB `json:"m"`
+	}
+
+	errBad := errors.New("bad code")
+	conv := func(c Code) (int, error) { return 0, errBad }
+
+	s := S{M: map[string][]IA{"k": {{Code: "x"}}}}
+	var d D
+	err := Convert(&s, &d, conv)
+	require.ErrorIs(t, err, errBad)
+	assert.EqualError(t, err, "M[k][0].Code: bad code")
+}
+
+type hookMoney struct {
+	Cents int64
+}
+
+func (m *hookMoney) ConvertFrom(src any) (bool, error) {
+	s, ok := src.(string)
+	if !ok {
+		return false, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return true, err
+	}
+	m.Cents = int64(f * 100)
+	return true, nil
+}
+
+type hookTags []string
+
+func (t hookTags) ConvertTo(dst any) (bool, error) {
+	p, ok := dst.(*string)
+	if !ok {
+		return false, nil
+	}
+	*p = strings.Join(t, ",")
+	return true, nil
+}
+
+type hookPerson struct {
+	First    string `json:"first"`
+	Last     string `json:"last"`
+	FullName string `json:"-"`
+	Steps    []string
+}
+
+func (p *hookPerson) BeforeConvert(src any) error {
+	p.Steps = append(p.Steps, "before")
+	return nil
+}
+
+func (p *hookPerson) AfterConvert(src any) error {
+	p.FullName = p.First + " " + p.Last
+	p.Steps = append(p.Steps, "after")
+	return nil
+}
+
+func TestConversionHooks(t *testing.T) {
+	type Person struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	}
+	type Cents struct{ Cents int64 }
+	type S struct {
+		Price  string   `json:"price"`
+		Tags   hookTags `json:"tags"`
+		Owner  Person   `json:"owner"`
+		Amount Cents    `json:"amount"`
+	}
+	type D struct {
+		Price  hookMoney  `json:"price"`
+		Tags   string     `json:"tags"`
+		Owner  hookPerson `json:"owner"`
+		Amount hookMoney  `json:"amount"`
+	}
+
+	p, err := BuildPlan[S, D](Options{})
+	require.NoError(t, err, "BuildPlan failed")
+	s := S{Price: "12.34", Tags: hookTags{"a", "b"}, Owner: Person{"Ada", "Lovelace"}, Amount: Cents{5}}
+	var d D
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Equal(t, int64(1234), d.Price.Cents)
+	assert.Equal(t, "a,b", d.Tags)
+	assert.Equal(t, "Ada Lovelace", d.Owner.FullName)
+	assert.Equal(t, []string{"before", "after"}, d.Owner.Steps)
+	// ConvertFrom declines non-strings, so struct mapping handles the value.
+	assert.Equal(t, int64(5), d.Amount.Cents)
+
+	s.Price = "oops"
+	err = p.Convert(&d, &s)
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "Price", fe.Path)
+}
+
+// hookPipe handles every source through ConvertFrom, except negative ones.
+type hookPipe struct {
+	X chan int `json:"x"`
+}
+
+func (p *hookPipe) ConvertFrom(src any) (bool, error) {
+	in, ok := src.(struct {
+		X int `json:"x"`
+	})
+	if !ok || in.X < 0 {
+		return false, nil
+	}
+	p.X = make(chan int, 1)
+	p.X <- in.X
+	return true, nil
+}
+
+func TestConversionHookWithInvalidFallback(t *testing.T) {
+	type S struct {
+		In struct {
+			X int `json:"x"`
+		} `json:"in"`
+	}
+	type D struct {
+		In hookPipe `json:"in"`
+	}
+	p, err := BuildPlan[S, D](Options{})
+	require.NoError(t, err, "a hook handling every value needs no valid fallback")
+	var d D
+	var s S
+	s.In.X = 4
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Equal(t, 4, <-d.In.X)
+
+	s.In.X = -1
+	err = p.Convert(&d, &s)
+	assert.ErrorContains(t, err, `no conversion rule for int -> chan int`, "declined values fail with the fallback's error")
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "In", fe.Path)
+}
+
+func TestAfterHooks(t *testing.T) {
+	type PersonA struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	}
+	type PersonB struct {
+		First    string `json:"first"`
+		Last     string `json:"last"`
+		FullName string `json:"fullName"`
+	}
+	type S struct {
+		Owner  PersonA            `json:"owner"`
+		Others []PersonA          `json:"others"`
+		ByID   map[string]PersonA `json:"byId"`
+	}
+	type D struct {
+		Owner  PersonB            `json:"owner"`
+		Others []PersonB          `json:"others"`
+		ByID   map[string]PersonB `json:"byId"`
+		Count  int                `json:"-"`
+	}
+
+	fullName := After(func(src *PersonA, dst *PersonB) error {
+		dst.FullName = src.First + " " + src.Last
+		return nil
+	})
+	count := After(func(src *S, dst *D) error {
+		dst.Count = 1 + len(src.Others) + len(src.ByID)
+		return nil
+	})
+
+	s := S{
+		Owner:  PersonA{"Ada", "Lovelace"},
+		Others: []PersonA{{"Alan", "Turing"}},
+		ByID:   map[string]PersonA{"g": {"Grace", "Hopper"}},
+	}
+	var d D
+	require.NoError(t, Convert(&s, &d, fullName, count), "Convert failed")
+	assert.Equal(t, "Ada Lovelace", d.Owner.FullName)
+	assert.Equal(t, "Alan Turing", d.Others[0].FullName)
+	assert.Equal(t, "Grace Hopper", d.ByID["g"].FullName)
+	assert.Equal(t, 3, d.Count)
+
+	p, err := BuildPlan[S, D](Options{}, fullName)
+	require.NoError(t, err, "BuildPlan failed")
+	var d2 D
+	require.NoError(t, p.Convert(&d2, &s), "Convert failed")
+	assert.Equal(t, "Ada Lovelace", d2.Owner.FullName)
+	assert.Zero(t, d2.Count)
+
+	_, err = BuildPlan[S, D](Options{}, After(func(a, b string) error { return nil }))
+	assert.Error(t, err, "expected invalid hook error")
+}
+
+func TestFieldTransforms(t *testing.T) {
+	type LineA struct {
+		Total int64 `json:"total"`
+	}
+	type LineB struct {
+		Total float64 `json:"total"`
+	}
+	type OrderA struct {
+		Total int64   `json:"total"`
+		Lines []LineA `json:"lines"`
+	}
+	type OrderB struct {
+		Total float64 `json:"total"`
+		Lines []LineB `json:"lines"`
+	}
+	type S struct {
+		Name   string `json:"name"`
+		Email  string `json:"email"`
+		Order  OrderA `json:"order"`
+		Refund OrderA `json:"refund"`
+	}
+	type D struct {
+		Name   string `json:"name"`
+		Email  string `json:"email"`
+		Order  OrderB `json:"order"`
+		Refund OrderB `json:"refund"`
+	}
+
+	dollars := func(v float64) float64 { return v / 100 }
+	p, err := BuildPlan[S, D](Options{},
+		Transform("Name", strings.TrimSpace),
+		Transform("email", strings.ToLower),
+		Transform("Order.Total", dollars),
+		Transform("Order.Lines.Total", dollars),
+	)
+	require.NoError(t, err, "BuildPlan failed")
+
+	s := S{
+		Name:   "  Ada ",
+		Email:  "ADA@EXAMPLE.COM",
+		Order:  OrderA{Total: 1250, Lines: []LineA{{Total: 250}}},
+		Refund: OrderA{Total: 300},
+	}
+	var d D
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Equal(t, "Ada", d.Name)
+	assert.Equal(t, "ada@example.com", d.Email)
+	assert.Equal(t, 12.5, d.Order.Total)
+	assert.Equal(t, 2.5, d.Order.Lines[0].Total)
+	// The same pair at another path is not transformed.
+	assert.Equal(t, 300.0, d.Refund.Total)
+
+	_, err = BuildPlan[S, D](Options{}, Transform("Order.Missing", dollars))
+	assert.ErrorContains(t, err, "order.missing")
+	_, err = BuildPlan[S, D](Options{}, Transform("Name", dollars))
+	assert.ErrorContains(t, err, "must convert string")
+}
+
+func TestComputedFields(t *testing.T) {
+	type UserA struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+		Title string `json:"title"`
+		Born  int    `json:"born"`
+	}
+	type UserB struct {
+		First       string `json:"first"`
+		DisplayName string `json:"-"`
+		Age         int    `json:"age"`
+	}
+	type S struct {
+		Owner   UserA   `json:"owner"`
+		Members []UserA `json:"members"`
+	}
+	type D struct {
+		Owner   UserB   `json:"owner"`
+		Members []UserB `json:"members"`
+		Summary string
+	}
+
+	display := func(u UserA) string { return u.Title + " " + u.First + " " + u.Last }
+	age := func(u *UserA) (int, error) { return 2026 - u.Born, nil }
+	p, err := BuildPlan[S, D](Options{},
+		Compute("Owner.DisplayName", display),
+		Compute("Owner.Age", age),
+		Compute("Members.Age", age),
+		Compute("Summary", func(s S, d *D) (string, error) {
+			return d.Owner.First + " +" + strconv.Itoa(len(d.Members)), nil
+		}),
+	)
+	require.NoError(t, err, "BuildPlan failed")
+
+	s := S{
+		Owner:   UserA{First: "Ada", Last: "Lovelace", Title: "Countess", Born: 1815},
+		Members: []UserA{{First: "Alan", Born: 1912}},
+	}
+	var d D
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Equal(t, "Countess Ada Lovelace", d.Owner.DisplayName)
+	assert.Equal(t, 211, d.Owner.Age)
+	assert.Equal(t, 114, d.Members[0].Age)
+	assert.Empty(t, d.Members[0].DisplayName)
+	assert.Equal(t, "Ada +1", d.Summary)
+
+	_, err = BuildPlan[S, D](Options{}, Compute("Owner.First", func(u UserA) string { return "" }))
+	assert.ErrorContains(t, err, "collides")
+	_, err = BuildPlan[S, D](Options{}, Compute("Owner.Age", func(u UserA) string { return "" }))
+	assert.ErrorContains(t, err, "must return int")
+	_, err = BuildPlan[S, D](Options{}, Compute("Owner.Missing", display))
+	assert.ErrorContains(t, err, "no exported field")
+}
+
+func TestConditionalFields(t *testing.T) {
+	type NoteA struct {
+		Visibility    string `json:"visibility"`
+		InternalNotes string `json:"internalNotes"`
+		Currency      string `json:"currency"`
+		Price         int    `json:"price"`
+	}
+	type NoteB struct {
+		Visibility    string `json:"visibility"`
+		InternalNotes string `json:"internalNotes"`
+		Currency      string `json:"targetCurrency"`
+		Price         int    `json:"price"`
+	}
+	type S struct {
+		Notes []NoteA `json:"notes"`
+	}
+	type D struct {
+		Notes []NoteB `json:"notes"`
+	}
+
+	p, err := BuildPlan[S, D](Options{},
+		When("Notes.InternalNotes", func(n NoteA) bool { return n.Visibility == "internal" }),
+		When("Notes.Price", func(n *NoteA, d *NoteB) bool { return d.Currency == "" || n.Currency == d.Currency }),
+	)
+	require.NoError(t, err, "BuildPlan failed")
+
+	s := S{Notes: []NoteA{
+		{Visibility: "internal", InternalNotes: "secret", Currency: "EUR", Price: 5},
+		{Visibility: "public", InternalNotes: "secret", Currency: "USD", Price: 7},
+	}}
+	var d D
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Equal(t, "secret", d.Notes[0].InternalNotes)
+	assert.Equal(t, 5, d.Notes[0].Price)
+	assert.Empty(t, d.Notes[1].InternalNotes)
+	assert.Equal(t, 7, d.Notes[1].Price)
+
+	// Predicates see the destination: skip the price on a currency mismatch.
+	src := NoteA{Currency: "USD", Price: 9}
+	dst := NoteB{Currency: "EUR", Price: 1}
+	np, err := BuildPlan[NoteA, NoteB](Options{},
+		When("Price", func(n *NoteA, d *NoteB) bool { return n.Currency == d.Currency }),
+	)
+	require.NoError(t, err, "BuildPlan failed")
+	require.NoError(t, np.Convert(&dst, &src), "Convert failed")
+	assert.Equal(t, 1, dst.Price)
+
+	_, err = BuildPlan[S, D](Options{}, When("Notes.Price", func(s S) bool { return true }))
+	assert.ErrorContains(t, err, "must take source")
+}
+
+func TestDefaults(t *testing.T) {
+	type ItemA struct {
+		Name string `json:"name"`
+	}
+	type ItemB struct {
+		Name    string        `json:"name" default:"unnamed"`
+		Qty     int           `json:"qty" default:"1"`
+		TTL     time.Duration `json:"ttl" default:"1m30s"`
+		Enabled *bool         `json:"enabled" default:"true"`
+		Tags    []string      `json:"tags" default:"[\"new\"]"`
+	}
+	type S struct {
+		Items  []ItemA          `json:"items"`
+		ByName map[string]ItemA `json:"byName"`
+		Region string           `json:"region"`
+	}
+	type D struct {
+		Items  []ItemB          `json:"items"`
+		ByName map[string]ItemB `json:"byName"`
+		Region string           `json:"region" default:"eu"`
+		Tier   string           `json:"tier"`
+	}
+
+	s := S{Items: []ItemA{{Name: "a"}, {}}, ByName: map[string]ItemA{"x": {}}}
+	var d D
+	require.NoError(t, Convert(&s, &d), "Convert failed")
+	assert.Equal(t, "eu", d.Region)
+	assert.Equal(t, "a", d.Items[0].Name)
+	assert.Equal(t, "unnamed", d.Items[1].Name)
+	assert.Equal(t, 1, d.Items[1].Qty)
+	assert.Equal(t, 90*time.Second, d.Items[1].TTL)
+	if assert.NotNil(t, d.Items[1].Enabled) {
+		assert.True(t, *d.Items[1].Enabled)
+	}
+	assert.Equal(t, []string{"new"}, d.Items[1].Tags)
+	assert.Equal(t, "unnamed", d.ByName["x"].Name)
+	// Reference-typed defaults are not shared between values.
+	d.Items[0].Tags[0] = "changed"
+	assert.Equal(t, []string{"new"}, d.Items[1].Tags)
+
+	p, err := BuildPlan[S, D](Options{},
+		Default("Tier", "gold"),
+		Default("Items.Qty", 5),
+		Default("region", "us"),
+	)
+	require.NoError(t, err, "BuildPlan failed")
+	d = D{}
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Equal(t, "gold", d.Tier)
+	assert.Equal(t, "us", d.Region)
+	assert.Equal(t, 5, d.Items[1].Qty)
+
+	p, err = BuildPlan[S, D](Options{DefaultTag: "-"})
+	require.NoError(t, err, "BuildPlan failed")
+	d = D{}
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Empty(t, d.Region)
+
+	type Bad struct {
+		Region string `json:"region"`
+		Qty    int    `json:"qty" default:"many"`
+	}
+	_, err = BuildPlan[S, Bad](Options{})
+	assert.ErrorContains(t, err, "invalid default tag")
+	_, err = BuildPlan[S, D](Options{}, Default("Items.Qty", "lots"))
+	assert.ErrorContains(t, err, "invalid syntax")
+}
+
+func TestDefaultValuesAreNotShared(t *testing.T) {
+	type S struct {
+		ID int `json:"id"`
+	}
+	type D struct {
+		ID   int            `json:"id"`
+		Tags []string       `json:"tags"`
+		M    map[string]int `json:"m"`
+		P    *[]int         `json:"p"`
+	}
+	p, err := BuildPlan[S, D](Options{}, Default("Tags", []string{"a"}), Default("M", map[string]int{"x": 1}), Default("P", &[]int{1}))
+	require.NoError(t, err, "BuildPlan failed")
+	var d1, d2 D
+	require.NoError(t, p.Convert(&d1, &S{}), "Convert failed")
+	d1.Tags[0], d1.M["x"], (*d1.P)[0] = "changed", 2, 2
+	require.NoError(t, p.Convert(&d2, &S{}), "Convert failed")
+	assert.Equal(t, D{Tags: []string{"a"}, M: map[string]int{"x": 1}, P: &[]int{1}}, d2, "every conversion gets its own copy")
+}
+
+func TestRequiredFields(t *testing.T) {
+	type LineA struct {
+		SKU string `json:"sku"`
+	}
+	type LineB struct {
+		SKU string `json:"sku,required"`
+	}
+	type S struct {
+		ID    string  `json:"id"`
+		Lines []LineA `json:"lines"`
+		Note  *string `json:"note"`
+	}
+	type D struct {
+		ID    string  `json:"id,required"`
+		Lines []LineB `json:"lines"`
+		Note  *string `json:"note"`
+	}
+
+	s := S{ID: "1", Lines: []LineA{{SKU: "a"}, {}}}
+	var d D
+	err := Convert(&s, &d)
+	require.ErrorIs(t, err, ErrRequired)
+	assert.EqualError(t, err, "Lines[1].SKU: required field is nil or zero")
+
+	s.Lines[1].SKU = "b"
+	require.NoError(t, Convert(&s, &d), "Convert failed")
+
+	p, err := BuildPlan[S, D](Options{}, Required("Note"))
+	require.NoError(t, err, "BuildPlan failed")
+	err = p.Convert(&d, &s)
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "Note", fe.Path)
+
+	// A renamed source field is caught at plan time.
+	type Renamed struct {
+		Identifier string `json:"identifier"`
+		Note       string `json:"note"`
+	}
+	_, err = BuildPlan[Renamed, D](Options{})
+	assert.ErrorContains(t, err, `required field "ID" has no source field`)
+}
+
+func TestFieldAliases(t *testing.T) {
+	type Customer struct {
+		CustomerID string `typeconv:"customer_id,alias=client_id,alias=cust_id"`
+		Name       string `typeconv:"name"`
+	}
+	type Current struct {
+		CustomerID string `typeconv:"customer_id"`
+		ClientID   string `typeconv:"client_id"`
+	}
+	type Legacy struct {
+		CustID   string `typeconv:"cust_id"`
+		ClientID string `typeconv:"client_id"`
+		Name     string `typeconv:"name"`
+	}
+	type Oldest struct {
+		CustID string `typeconv:"cust_id"`
+	}
+
+	opts := Options{Tag: "typeconv"}
+	p1, err := BuildPlan[Current, Customer](opts)
+	require.NoError(t, err, "BuildPlan failed")
+	var d Customer
+	require.NoError(t, p1.Convert(&d, &Current{CustomerID: "new", ClientID: "old"}))
+	assert.Equal(t, "new", d.CustomerID, "primary name wins over aliases")
+
+	p2, err := BuildPlan[Legacy, Customer](opts)
+	require.NoError(t, err, "BuildPlan failed")
+	d = Customer{}
+	require.NoError(t, p2.Convert(&d, &Legacy{CustID: "c", ClientID: "b", Name: "n"}))
+	assert.Equal(t, "b", d.CustomerID, "aliases apply in declaration order")
+	assert.Equal(t, "n", d.Name)
+
+	p3, err := BuildPlan[Oldest, Customer](opts)
+	require.NoError(t, err, "BuildPlan failed")
+	d = Customer{}
+	require.NoError(t, p3.Convert(&d, &Oldest{CustID: "c"}))
+	assert.Equal(t, "c", d.CustomerID)
+}
+
+func TestFieldAliasesFromMaps(t *testing.T) {
+	type Address struct {
+		Zip string `json:"zip,alias=postcode"`
+	}
+	type Customer struct {
+		CustomerID string    `json:"customer_id,alias=client_id,alias=cust_id"`
+		Name       string    `json:"name"`
+		Addresses  []Address `json:"addresses"`
+	}
+	type Event struct {
+		Customer map[string]any `json:"customer"`
+	}
+	type Envelope struct {
+		Customer Customer `json:"customer"`
+	}
+	p, err := BuildPlan[Event, Envelope](Options{})
+	require.NoError(t, err, "BuildPlan failed")
+
+	doc := map[string]any{"cust_id": "c", "client_id": "b", "name": "n", "addresses": []any{map[string]any{"postcode": "123"}}}
+	var d Envelope
+	require.NoError(t, p.Convert(&d, &Event{Customer: doc}), "Convert failed")
+	assert.Equal(t, Customer{CustomerID: "b", Name: "n", Addresses: []Address{{Zip: "123"}}}, d.Customer,
+		"aliases apply in declaration order, in nested documents too")
+	assert.Contains(t, doc, "cust_id", "the source document is not modified")
+
+	d = Envelope{}
+	require.NoError(t, p.Convert(&d, &Event{Customer: map[string]any{"Customer_ID": "a", "client_id": "b"}}), "Convert failed")
+	assert.Equal(t, "a", d.Customer.CustomerID, "the field's own key wins, in any case")
+}
+
+func TestTypeconvTag(t *testing.T) {
+	type API struct {
+		ID      string   `json:"id"`
+		Name    string   `json:"display_name"`
+		Created string   `json:"created"`
+		Secret  string   `json:"secret"`
+		Avatar  string   `json:"avatar"`
+		Tags    []string `json:"tags"`
+		Version int      `json:"version"`
+	}
+	type DB struct {
+		ID      string    `json:"pk" typeconv:"id,dir=read"`
+		Name    string    `json:"name" typeconv:"display_name,alias=name"`
+		Created time.Time `typeconv:"created,format=DateOnly"`
+		Secret  string    `typeconv:"-"`
+		Avatar  []byte    `typeconv:"avatar,format=base64"`
+		Tags    []string  `typeconv:"tags,default=[\"a\",\"b\"]"`
+		Version int       `typeconv:"version,dir=write,required"`
+	}
+
+	api := API{ID: "1", Name: "Ada", Created: "2025-09-03", Secret: "s", Avatar: "aGk=", Version: 2}
+	var db DB
+	require.NoError(t, Convert(&api, &db), "Convert failed")
+	assert.Empty(t, db.ID, "read-only field is not written")
+	assert.Equal(t, "Ada", db.Name)
+	assert.Equal(t, time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC), db.Created)
+	assert.Empty(t, db.Secret)
+	assert.Equal(t, []byte("hi"), db.Avatar)
+	assert.Equal(t, []string{"a", "b"}, db.Tags)
+	assert.Equal(t, 2, db.Version)
+
+	db.ID = "9"
+	var back API
+	require.NoError(t, Convert(&db, &back), "Convert failed")
+	assert.Equal(t, "9", back.ID)
+	assert.Equal(t, "2025-09-03", back.Created)
+	assert.Equal(t, "aGk=", back.Avatar)
+	assert.Zero(t, back.Version, "write-only field is not read")
+
+	type Malformed struct {
+		ID string `typeconv:"id,dir=sideways"`
+	}
+	_, err := BuildPlan[API, Malformed](Options{})
+	assert.ErrorContains(t, err, "malformed typeconv tag on field ID")
+	type Unknown struct {
+		ID string `typeconv:"id,omitempty"`
+	}
+	_, err = BuildPlan[API, Unknown](Options{})
+	assert.ErrorContains(t, err, `unknown option "omitempty"`)
+	type BadFormat struct {
+		Version int `typeconv:"version,format=base64"`
+	}
+	_, err = BuildPlan[API, BadFormat](Options{})
+	assert.ErrorContains(t, err, "does not apply")
+}
+
+func TestTypeconvTagWithoutName(t *testing.T) {
+	type Order struct {
+		Total int64 `json:"total_cents"`
+		Note  string
+	}
+	type Row struct {
+		Total int64  `json:"total_cents" typeconv:",required"`
+		Note  string `json:"-" typeconv:",required"`
+	}
+	var r Row
+	require.NoError(t, Convert(&Order{Total: 5, Note: "n"}, &r), "Convert failed")
+	assert.Equal(t, Row{Total: 5, Note: "n"}, r, "the name comes from Options.Tag, else the field name")
+	err := Convert(&Order{Note: "n"}, &r)
+	assert.ErrorIs(t, err, ErrRequired)
+}
+
+func TestNamedConverters(t *testing.T) {
+	type Order struct {
+		Total    int64
+		Shipping int64
+		Code     string
+	}
+	type View struct {
+		Total    string `typeconv:"total,conv=cents"`
+		Shipping int64
+		Code     string `typeconv:"code,conv=upper"`
+	}
+	cents := Named("cents", func(c int64) string { return fmt.Sprintf("%d.%02d", c/100, c%100) })
+	upper := Named("upper", strings.ToUpper)
+
+	var v View
+	require.NoError(t, Convert(&Order{Total: 1250, Shipping: 300, Code: "ab"}, &v, cents, upper), "Convert failed")
+	assert.Equal(t, View{Total: "12.50", Shipping: 300, Code: "AB"}, v, "named converters apply only to tagged fields")
+
+	p, err := BuildPlan[Order, View](Options{}, cents, upper)
+	require.NoError(t, err, "BuildPlan failed")
+	v = View{}
+	require.NoError(t, p.Convert(&v, &Order{Total: 5}), "Convert failed")
+	assert.Equal(t, "0.05", v.Total)
+
+	_, err = BuildPlan[Order, View](Options{}, cents)
+	assert.ErrorContains(t, err, `field "Code": unknown converter "upper"`)
+	_, err = BuildPlan[Order, View](Options{}, cents, Named("upper", strings.TrimSpace), Named("upper", strings.ToUpper))
+	assert.ErrorContains(t, err, `duplicate converter name "upper"`)
+	_, err = BuildPlan[Order, View](Options{}, upper, Named("cents", strconv.Itoa))
+	assert.ErrorContains(t, err, `converter "cents" converts int -> string, not int64 -> string`)
+}
+
+func TestPlanDescribe(t *testing.T) {
+	p, err := BuildPlan[A, B](Options{})
+	require.NoError(t, err, "BuildPlan failed")
+	d := p.Describe()
+	assert.Equal(t, "typeconv.A", d.Source)
+	rules := map[string]Rule{}
+	for _, f := range d.Fields {
+		rules[f.Source+" -> "+f.Destination] = f.Rule
+	}
+	assert.Equal(t, map[string]Rule{
+		"ID -> ID":                       RuleAssign,
+		"Name -> Name":                   RuleAssign,
+		"Meta -> Meta":                   RuleAssign,
+		"Items -> Items":                 RuleSlice,
+		"Items[] -> Items[]":             RuleStruct,
+		"Items[].Value -> Items[].Value": RuleAssign,
+		"Untagged -> Untagged":           RuleAssign,
+		"Custom -> Custom":               RuleJSON,
+	}, rules)
+	assert.Contains(t, p.Explain(), "Custom         -> Custom         typeconv.CustomTypeA -> typeconv.CustomTypeB  json\n")
+
+	type NodeA struct {
+		V    int    `json:"v"`
+		Next *NodeA `json:"next"`
+	}
+	type NodeB struct {
+		V    int64  `json:"v" typeconv:"v,required"`
+		Next *NodeB `json:"next"`
+		Sum  int    `json:"-"`
+		Kind string `default:"leaf"`
+	}
+	np, err := BuildPlan[NodeA, NodeB](Options{},
+		Compute("Sum", func(n NodeA) int { return n.V }),
+		When("V", func(n NodeA) bool { return n.V > 0 }))
+	require.NoError(t, err, "BuildPlan failed")
+	assert.Equal(t, []FieldDescription{
+		{Source: "V", Destination: "V", SourceType: "int", DestinationType: "int64", Rule: RuleConvert, Conditional: true, Required: true},
+		{Source: "Next", Destination: "Next", SourceType: "*typeconv.NodeA", DestinationType: "*typeconv.NodeB", Rule: RuleStruct},
+		{Source: "Next.V", Destination: "Next.V", SourceType: "int", DestinationType: "int64", Rule: RuleConvert, Required: true},
+		{Source: "Next.Next", Destination: "Next.Next", SourceType: "*typeconv.NodeA", DestinationType: "*typeconv.NodeB", Rule: RuleStruct, Recursive: true},
+		{Destination: "Next.Kind", DestinationType: "string", Rule: RuleDefault, Default: true},
+		{Destination: "Sum", DestinationType: "int", Rule: RuleComputed},
+		{Destination: "Kind", DestinationType: "string", Rule: RuleDefault, Default: true},
+	}, np.Describe().Fields)
+
+	data, err := json.Marshal(np.Describe())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `{"source":"Next.Next","destination":"Next.Next","sourceType":"*typeconv.NodeA","destinationType":"*typeconv.NodeB","rule":"struct","recursive":true}`)
+}
+
+func TestPlanTimeValidation(t *testing.T) {
+	type ItemS struct{ Qty string }
+	type ItemD struct{ Qty bool }
+	type LegacyS struct{ Codes []string }
+	type LegacyD struct{ Codes []int }
+	type S struct {
+		Name   string
+		Items  []ItemS
+		ByKey  map[string]*ItemS
+		Legacy LegacyS
+		Notify chan int
+		Ok     time.Time
+	}
+	type D struct {
+		Name   string
+		Items  []ItemD
+		ByKey  map[string]ItemD
+		Legacy map[string][]int
+		Notify string
+		Ok     string
+	}
+
+	// Every field below fails only on data, so a nil or empty first record
+	// would not catch it at run time.
+	_, err := BuildPlan[S, D](Options{})
+	var pe *PlanError
+	require.ErrorAs(t, err, &pe)
+	// ByKey shares the ItemS -> ItemD plan with Items, so its pair is
+	// reported once.
+	require.Len(t, pe.Errs, 3, "%v", err)
+	assert.ErrorContains(t, pe.Errs[0], `field "Items.Qty": no conversion rule for string -> bool`)
+	assert.ErrorContains(t, pe.Errs[1], `field "Legacy": no conversion rule for typeconv.LegacyS -> map[string][]int: json: string encodes as string, which int cannot decode`)
+	assert.ErrorContains(t, pe.Errs[2], `field "Notify": no conversion rule for chan int -> string: json: unsupported type chan int`)
+	assert.ErrorContains(t, Convert(&S{}, &D{}), "3 fields cannot be converted")
+
+	// Types that can round-trip through JSON still plan.
+	_, err = BuildPlan[struct{ Legacy LegacyS }, struct{ Legacy LegacyD }](Options{})
+	assert.ErrorContains(t, err, `field "Legacy.Codes": no conversion rule for string -> int`)
+	_, err = BuildPlan[struct{ Legacy LegacyS }, struct{ Legacy map[string]any }](Options{})
+	assert.NoError(t, err)
+	_, err = BuildPlan[struct{ When time.Time }, struct{ When string }](Options{})
+	assert.NoError(t, err)
+}
+
+func TestConversionPolicy(t *testing.T) {
+	type S struct {
+		Count   int32
+		Ratio   float64
+		Amount  string
+		Timeout string
+		Joined  time.Time
+	}
+	type D struct {
+		Count   int64
+		Ratio   float32
+		Amount  int
+		Timeout time.Duration
+		Joined  string
+	}
+
+	// The default policy narrows floats with reflect.Convert and parses
+	// strings through JSON only when they happen to be JSON numbers.
+	_, err := BuildPlan[S, D](Options{})
+	assert.ErrorContains(t, err, "no conversion rule for string -> int: json: string encodes as string")
+
+	_, err = BuildPlan[S, D](Options{Policy: PolicyStrict})
+	var pe *PlanError
+	require.ErrorAs(t, err, &pe)
+	require.Len(t, pe.Errs, 4, "%v", err)
+	assert.ErrorContains(t, pe.Errs[0], `field "Ratio": no conversion rule for float64 -> float32 allowed by policy assign|lossless-numeric (needs convert)`)
+	assert.ErrorContains(t, pe.Errs[1], `field "Amount": no conversion rule for string -> int allowed by policy assign|lossless-numeric (needs parse)`)
+	assert.ErrorContains(t, pe.Errs[2], `field "Timeout": no conversion rule for string -> time.Duration allowed by policy assign|lossless-numeric (needs parse)`)
+	assert.ErrorContains(t, pe.Errs[3], `field "Joined": no conversion rule for time.Time -> string allowed by policy assign|lossless-numeric (needs text)`)
+
+	p, err := BuildPlan[S, D](Options{Policy: PolicyStrict | AllowConvert | AllowText | AllowParse})
+	require.NoError(t, err, "BuildPlan failed")
+	joined := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var d D
+	require.NoError(t, p.Convert(&d, &S{Count: 7, Ratio: 0.5, Amount: "42", Timeout: "1m30s", Joined: joined}))
+	assert.Equal(t, D{Count: 7, Ratio: 0.5, Amount: 42, Timeout: 90 * time.Second, Joined: "2025-01-02T03:04:05Z"}, d)
+	rules := map[string]Rule{}
+	for _, f := range p.Describe().Fields {
+		rules[f.Destination] = f.Rule
+	}
+	assert.Equal(t, map[string]Rule{"Count": RuleConvert, "Ratio": RuleConvert, "Amount": RuleParse, "Timeout": RuleParse, "Joined": RuleText}, rules)
+
+	var back S
+	bp, err := BuildPlan[D, S](Options{Policy: AllowAssign | AllowConvert | AllowText | AllowParse})
+	require.NoError(t, err, "BuildPlan failed")
+	require.NoError(t, bp.Convert(&back, &d))
+	assert.Equal(t, S{Count: 7, Ratio: 0.5, Amount: "42", Timeout: "1m30s", Joined: joined}, back)
+	assert.ErrorContains(t, p.Convert(&d, &S{Amount: "x"}), `Amount: strconv.ParseInt: parsing "x": invalid syntax`)
+
+	assert.True(t, losslessNumeric(reflect.TypeOf(uint32(0)), reflect.TypeOf(int64(0))))
+	assert.True(t, losslessNumeric(reflect.TypeOf(int16(0)), reflect.TypeOf(float32(0))))
+	assert.False(t, losslessNumeric(reflect.TypeOf(int64(0)), reflect.TypeOf(float64(0))))
+	assert.False(t, losslessNumeric(reflect.TypeOf(int8(0)), reflect.TypeOf(uint64(0))))
+}
+
+// countingCodec wraps JSONCodec and counts round trips.
+type countingCodec struct {
+	JSONCodec
+	n *int
+}
+
+func (c countingCodec) Marshal(v any) ([]byte, error) {
+	*c.n++
+	return c.JSONCodec.Marshal(v)
+}
+
+// anyCodec is comparable by type, but not when inner holds a slice.
+type anyCodec struct {
+	JSONCodec
+	inner any
+}
+
+func TestFallbackCodec(t *testing.T) {
+	type Extra struct {
+		Score int  `json:"score"`
+		Flag  bool `json:"flag"`
+	}
+	type Narrow struct {
+		Rank int `json:"rank"`
+	}
+	type S struct {
+		Extra Extra `json:"extra"`
+		Other Extra `json:"other"`
+	}
+	type D struct {
+		Extra map[string]any `json:"extra"`
+		Other Narrow         `json:"other"`
+	}
+	s := S{Extra: Extra{Score: 3, Flag: true}, Other: Extra{Score: 1}}
+
+	var d D
+	require.NoError(t, Convert(&s, &d), "Convert failed")
+	assert.Equal(t, map[string]any{"score": 3.0, "flag": true}, d.Extra)
+
+	p, err := BuildPlan[S, D](Options{Codec: JSONCodec{UseNumber: true}})
+	require.NoError(t, err, "BuildPlan failed")
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Equal(t, map[string]any{"score": json.Number("3"), "flag": true}, d.Extra)
+	again, err := BuildPlan[S, D](Options{Codec: JSONCodec{UseNumber: true}})
+	require.NoError(t, err)
+	assert.Same(t, p, again, "the codec is part of the cache key")
+
+	_, err = BuildPlan[S, D](Options{Codec: JSONCodec{DisallowUnknownFields: true}})
+	var pe *PlanError
+	require.ErrorAs(t, err, &pe, "unknown fields are reported at plan time")
+	assert.ErrorContains(t, err, "typeconv.Extra has fields unknown to typeconv.Narrow, which DisallowUnknownFields rejects: flag, score")
+	// The db tags do not overlap, so the nested pair falls back to JSON.
+	type Sub struct {
+		Score int `db:"s" json:"score"`
+	}
+	type Super struct {
+		Score int  `db:"x" json:"score"`
+		Flag  bool `db:"f" json:"flag"`
+	}
+	type Outer struct {
+		V Sub `db:"v"`
+	}
+	type OuterD struct {
+		V Super `db:"v"`
+	}
+	p2, err := BuildPlan[Outer, OuterD](Options{Tag: "db", Codec: JSONCodec{DisallowUnknownFields: true}})
+	require.NoError(t, err, "destinations may have more fields than their sources")
+	var od OuterD
+	require.NoError(t, p2.Convert(&od, &Outer{V: Sub{Score: 2}}))
+	assert.Equal(t, 2, od.V.Score)
+
+	n := 0
+	p, err = BuildPlan[S, D](Options{Codec: countingCodec{n: &n}})
+	require.NoError(t, err, "BuildPlan failed")
+	n = 0
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	assert.Equal(t, 2, n, "custom codecs handle every fallback")
+
+	p, err = BuildPlan[S, D](Options{Codec: anyCodec{inner: []int{1}}})
+	require.NoError(t, err, "non-comparable codec values are not cached")
+	again, err = BuildPlan[S, D](Options{Codec: anyCodec{inner: []int{1}}})
+	require.NoError(t, err)
+	assert.NotSame(t, p, again)
+	require.NoError(t, p.Convert(&d, &s), "Convert failed")
+	p, err = BuildPlan[S, D](Options{Codec: anyCodec{inner: 1}})
+	require.NoError(t, err)
+	again, err = BuildPlan[S, D](Options{Codec: anyCodec{inner: 1}})
+	require.NoError(t, err)
+	assert.Same(t, p, again)
+}
+
+// level encodes its zero value as a sentinel.
+type level struct{ N int }
+
+func (l level) MarshalJSON() ([]byte, error) {
+	if l.N == 0 {
+		return []byte(`"UNKNOWN"`), nil
+	}
+	return json.Marshal(fmt.Sprintf("L%d", l.N))
+}
+
+// region decodes an empty code to its default.
+type region struct{ Code string }
+
+func (r *region) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &r.Code); err != nil {
+		return err
+	}
+	if r.Code == "" {
+		r.Code = "us"
+	}
+	return nil
+}
+
+func TestJSONFallbackZeroValues(t *testing.T) {
+	type Extra struct {
+		Score int `json:"score"`
+	}
+	type S struct {
+		Level  level     `json:"level"`
+		Region string    `json:"region"`
+		Extra  Extra     `json:"extra"`
+		When   time.Time `json:"when"`
+	}
+	type D struct {
+		Level  string         `json:"level"`
+		Region region         `json:"region"`
+		Extra  map[string]any `json:"extra"`
+		When   *time.Time     `json:"when"`
+	}
+
+	var d D
+	require.NoError(t, Convert(&S{}, &d), "Convert failed")
+	assert.Equal(t, "UNKNOWN", d.Level, "MarshalJSON runs for zero values")
+	assert.Equal(t, region{Code: "us"}, d.Region, "UnmarshalJSON runs for zero values")
+	assert.Equal(t, map[string]any{"score": 0.0}, d.Extra, "a zero struct decodes into a non-nil map")
+	require.NotNil(t, d.When)
+	assert.True(t, d.When.IsZero())
+
+	d.Extra["stale"] = true
+	require.NoError(t, Convert(&S{Level: level{N: 2}, Extra: Extra{Score: 1}}, &d), "Convert failed")
+	assert.Equal(t, "L2", d.Level)
+	assert.Equal(t, map[string]any{"score": 1.0}, d.Extra, "the destination is replaced, not merged")
+
+	// Zero values that round-trip to zero still skip the codec.
+	type Narrow struct {
+		Rank int `json:"rank"`
+	}
+	n := 0
+	p, err := BuildPlan[struct{ Extra Extra }, struct{ Extra Narrow }](Options{Codec: countingCodec{n: &n}})
+	require.NoError(t, err, "BuildPlan failed")
+	n = 0
+	var nd struct{ Extra Narrow }
+	require.NoError(t, p.Convert(&nd, &struct{ Extra Extra }{}))
+	assert.Zero(t, n)
+	require.NoError(t, p.Convert(&nd, &struct{ Extra Extra }{Extra: Extra{Score: 1}}))
+	assert.Equal(t, 1, n)
+}
+
+func BenchmarkConvertWithConverters(b *testing.B) {
+	cconv := func(src *CustomTypeA, dst *CustomTypeB) error {
+		v, err := strconv.Atoi(string(*src))
+		*dst = CustomTypeB(v)
+		return err
+	}
+	a := A{ID: "bench", Name: "world", Meta: map[string]int{"x": 42}, Items: []ItemA{{Value: 5}, {Value: 6}},
+		Untagged: "untagged-value", Custom: CustomTypeA("1234")}
+	b.Run("Typeconv", func(b *testing.B) {
+		var out B
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := Convert(&a, &out, cconv); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Converter", func(b *testing.B) {
+		c, err := New(Options{}, cconv)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var out B
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := ConvertWith(c, &a, &out); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("JSON", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			data, _ := json.Marshal(a)
+			var out B
+			if err := json.Unmarshal(data, &out); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkConvertParallel converts with cached plans from every P, where
+// plan cache hits must not serialize.
+func BenchmarkConvertParallel(b *testing.B) {
+	type S struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type D struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		s := S{ID: 1, Name: "a"}
+		var d D
+		for pb.Next() {
+			if err := Convert(&s, &d); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkJSONFallback(b *testing.B) {
+	type S struct {
+		Tags []string `json:"tags"`
+		N    int      `json:"n"`
+	}
+	type Wrap struct {
+		V S `json:"v"`
+	}
+	type WrapD struct {
+		V map[string]any `json:"v"`
+	}
+	p, err := BuildPlan[Wrap, WrapD](Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	src := Wrap{V: S{Tags: []string{"a", "b", "c"}, N: 7}}
+	b.ReportAllocs()
+	for b.Loop() {
+		var d WrapD
+		if err := p.Convert(&d, &src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestPlanCaching(t *testing.T) {
 	type S struct {
 		A int `json:"a"`
@@ -326,6 +1625,485 @@ func TestPlanCaching(t *testing.T) {
 	assert.Equal(t, p1, p2, "expected cached plan pointer equality")
 }
 
+func TestPlanCachingWithExtensions(t *testing.T) {
+	type S struct {
+		N int `json:"n"`
+	}
+	type D struct {
+		N string `json:"n"`
+		M string `json:"m"`
+	}
+	suffixed := func(suffix string) func(int) string {
+		return func(n int) string { return strconv.Itoa(n) + suffix }
+	}
+	conv := suffixed("a")
+	p0, err := BuildPlan[S, D](Options{}, conv, Default("M", "x"))
+	require.NoError(t, err, "BuildPlan failed")
+	p1, err := BuildPlan[S, D](Options{}, conv, Default("M", "x"))
+	require.NoError(t, err, "BuildPlan failed")
+	assert.NotSame(t, p0, p1, "plans of new extensions are cached from their second build")
+	p2, err := BuildPlan[S, D](Options{}, conv, Default("M", "x"))
+	require.NoError(t, err, "BuildPlan failed")
+	assert.Same(t, p1, p2, "the same extensions share the cached plan")
+
+	p3, err := BuildPlan[S, D](Options{}, suffixed("b"), Default("M", "x"))
+	require.NoError(t, err, "BuildPlan failed")
+	assert.NotSame(t, p1, p3, "closures are told apart by identity, not code")
+	p4, err := BuildPlan[S, D](Options{}, conv, Default("M", "y"))
+	require.NoError(t, err, "BuildPlan failed")
+	assert.NotSame(t, p1, p4)
+
+	var d D
+	require.NoError(t, Convert(&S{N: 1}, &d, suffixed("c"), Default("M", "z")), "Convert failed")
+	assert.Equal(t, D{N: "1c", M: "z"}, d)
+	require.NoError(t, Convert(&S{N: 1}, &d, suffixed("d"), Default("M", "z")), "Convert failed")
+	assert.Equal(t, D{N: "1d", M: "z"}, d)
+
+	for range 3 {
+		_, err = BuildPlan[S, D](Options{}, conv, Default("M", "x"), Default("M", "y"))
+		assert.ErrorContains(t, err, "duplicate default", "errors are not cached")
+	}
+
+	c, err := New(Options{})
+	require.NoError(t, err)
+	for i := range 100 {
+		require.NoError(t, ConvertWith(c, &S{N: i}, &d, func(n int) string { return strconv.Itoa(n + i) }))
+		assert.Equal(t, strconv.Itoa(2*i), d.N)
+	}
+	assert.Zero(t, c.Stats().Size, "plans of closures created for every call are not cached")
+}
+
+func TestCaches(t *testing.T) {
+	type S struct {
+		A int `json:"a"`
+	}
+	type D1 struct {
+		A int `json:"a"`
+	}
+	type D2 struct {
+		A int64 `json:"a"`
+	}
+	type D3 struct {
+		A string `json:"a"`
+	}
+	ResetCaches()
+	t.Cleanup(func() { SetCacheLimits(DefaultPlanCacheLimit, DefaultFieldCacheLimit) })
+
+	var wg sync.WaitGroup
+	plans := make([]*Plan[S, D1], 16)
+	for i := range plans {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := BuildPlan[S, D1](Options{})
+			assert.NoError(t, err)
+			plans[i] = p
+		}()
+	}
+	wg.Wait()
+	for _, p := range plans[1:] {
+		assert.Same(t, plans[0], p, "concurrent first builds share one plan")
+	}
+	st := Stats().Plans
+	assert.Equal(t, uint64(1), st.Builds)
+	assert.Equal(t, uint64(16), st.Hits+st.Misses)
+	assert.Equal(t, 1, st.Size)
+	assert.Equal(t, DefaultPlanCacheLimit, st.Limit)
+	assert.Equal(t, 2, Stats().FieldMaps.Size)
+
+	SetCacheLimits(2, 0)
+	_, err := BuildPlan[S, D2](Options{})
+	require.NoError(t, err)
+	_, err = BuildPlan[S, D1](Options{}) // D1 becomes most recently used
+	require.NoError(t, err)
+	_, err = BuildPlan[S, D3](Options{})
+	require.NoError(t, err)
+	st = Stats().Plans
+	assert.Equal(t, 2, st.Size)
+	assert.Equal(t, uint64(1), st.Evictions, "S -> D2 is evicted")
+	p, err := BuildPlan[S, D1](Options{})
+	require.NoError(t, err)
+	assert.Same(t, plans[0], p)
+
+	ResetCaches()
+	assert.Equal(t, CacheCounters{Limit: 2}, Stats().Plans)
+	p, err = BuildPlan[S, D1](Options{})
+	require.NoError(t, err)
+	assert.NotSame(t, plans[0], p, "reset drops cached plans")
+	var d D1
+	require.NoError(t, plans[0].Convert(&d, &S{A: 1}), "plans built before a reset stay usable")
+	assert.Equal(t, 1, d.A)
+}
+
+func TestConverterInstance(t *testing.T) {
+	type S struct {
+		ID    int64  `db:"id"`
+		Price int64  `db:"price"`
+		Name  string `db:"name"`
+	}
+	type D struct {
+		ID    int64  `db:"id"`
+		Price string `db:"price" typeconv:"price,conv=cents"`
+		Name  string `db:"name"`
+	}
+	cents := Named("cents", func(c int64) string { return fmt.Sprintf("%d.%02d", c/100, c%100) })
+	c, err := New(Options{Tag: "db"}, cents)
+	require.NoError(t, err, "New failed")
+	assert.Equal(t, "db", c.Options().Tag)
+	assert.Equal(t, JSONCodec{}, c.Options().Codec, "options are filled with defaults")
+
+	var d D
+	require.NoError(t, ConvertWith(c, &S{ID: 1, Price: 1250, Name: "a"}, &d), "ConvertWith failed")
+	assert.Equal(t, D{ID: 1, Price: "12.50", Name: "a"}, d, "instance options and extensions apply")
+
+	d = D{}
+	require.NoError(t, ConvertWith(c, &S{Price: 5, Name: "a"}, &d, Transform("Name", strings.ToUpper)))
+	assert.Equal(t, D{Price: "0.05", Name: "A"}, d, "per-call extensions add to the instance's")
+
+	p1, err := BuildPlanWith[S, D](c)
+	require.NoError(t, err)
+	p2, err := BuildPlanWith[S, D](c)
+	require.NoError(t, err)
+	assert.Same(t, p1, p2, "plans are cached in the instance")
+	assert.Equal(t, 1, c.Stats().Size, "the per-call transform was used once")
+
+	other, err := New(Options{Tag: "db"}, Named("cents", func(c int64) string { return strconv.FormatInt(c, 10) }))
+	require.NoError(t, err)
+	d = D{}
+	require.NoError(t, ConvertWith(other, &S{Price: 1250}, &d))
+	assert.Equal(t, "1250", d.Price, "instances do not share plans")
+	assert.Equal(t, 1, c.Stats().Size)
+
+	_, err = BuildPlan[S, D](Options{Tag: "db"})
+	assert.ErrorContains(t, err, `unknown converter "cents"`, "the default converter does not see instance extensions")
+	assert.Same(t, defaultConverter, DefaultConverter())
+
+	c.ResetCache()
+	assert.Equal(t, CacheCounters{Limit: DefaultPlanCacheLimit}, c.Stats())
+
+	_, err = New(Options{}, 42)
+	assert.Error(t, err, "New rejects invalid extensions")
+}
+
+// eventLog records observed events.
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) Observe(ctx context.Context, e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+// take returns the recorded events as "kind path" strings and clears them.
+func (l *eventLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []string
+	for _, e := range l.events {
+		out = append(out, strings.TrimSpace(e.Kind.String()+" "+e.Path))
+	}
+	l.events = nil
+	return out
+}
+
+func TestObserver(t *testing.T) {
+	type Meta struct {
+		A int `json:"a"`
+	}
+	type S struct {
+		Items []int64        `json:"items"`
+		Meta  map[string]int `json:"meta"`
+		Price int64          `json:"price"`
+	}
+	type D struct {
+		Items []string `json:"items"`
+		Meta  Meta     `json:"meta"`
+		Price string   `json:"price"`
+	}
+	log := &eventLog{}
+	c, err := New(Options{Observer: log}, func(v int64) string { return strconv.FormatInt(v, 10) })
+	require.NoError(t, err, "New failed")
+
+	var d D
+	src := S{Items: []int64{1, 2}, Meta: map[string]int{"a": 3}, Price: 4}
+	require.NoError(t, ConvertWith(c, &src, &d), "ConvertWith failed")
+	assert.Equal(t, D{Items: []string{"1", "2"}, Meta: Meta{A: 3}, Price: "4"}, d)
+	log.mu.Lock()
+	plan, end := log.events[0], log.events[len(log.events)-1]
+	log.mu.Unlock()
+	assert.False(t, plan.Cached)
+	assert.Equal(t, reflect.TypeOf(S{}), plan.Source)
+	assert.Equal(t, reflect.TypeOf(D{}), end.Destination)
+	assert.Positive(t, end.Duration)
+	assert.Equal(t, []string{"plan", "convert-start", "custom Items[0]", "custom Items[1]", "fallback Meta", "custom Price", "convert-end"}, log.take())
+
+	require.NoError(t, ConvertWith(c, &src, &d))
+	log.mu.Lock()
+	assert.True(t, log.events[0].Cached, "second lookup is served from the cache")
+	log.mu.Unlock()
+	log.take()
+
+	p, err := BuildPlan[S, D](Options{Observer: log}, func(v int64) string { return "x" })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plan"}, log.take())
+	require.NoError(t, p.Convert(&d, &S{Price: 1}))
+	assert.Equal(t, []string{"convert-start", "fallback Meta", "custom Price", "convert-end"}, log.take(), "nil slices skip their element converters")
+
+	type Line struct {
+		Meta map[string]int `json:"meta"`
+	}
+	type LineD struct {
+		Meta Meta `json:"meta"`
+	}
+	type Order struct {
+		Head  Line            `json:"head"`
+		Lines map[string]Line `json:"lines"`
+	}
+	type OrderD struct {
+		Head  LineD            `json:"head"`
+		Lines map[string]LineD `json:"lines"`
+	}
+	po, err := BuildPlan[Order, OrderD](Options{Observer: log})
+	require.NoError(t, err)
+	log.take()
+	require.NoError(t, po.Convert(&OrderD{}, &Order{Lines: map[string]Line{"k": {}}}))
+	assert.Equal(t, []string{"convert-start", "fallback Head.Meta", "fallback Lines[k].Meta", "convert-end"}, log.take(),
+		"a nested pair shared by several fields reports the path being converted")
+
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c, err = New(Options{Observer: SlogObserver(logger)}, func(v int64) string { return "x" })
+	require.NoError(t, err)
+	require.NoError(t, ConvertWith(c, &src, &d))
+	assert.Contains(t, buf.String(), `msg="typeconv fallback" src=map[string]int dst=typeconv.Meta path=Meta duration=`)
+	assert.Contains(t, buf.String(), `msg="typeconv plan" src=typeconv.S dst=typeconv.D cached=false`)
+}
+
+func TestUnsafeCopy(t *testing.T) {
+	type ID string
+	type Celsius float64
+	type Inner struct {
+		Tags []string `json:"tags"`
+	}
+	type Base struct {
+		Created int64 `json:"created"`
+	}
+	type S struct {
+		*Base
+		ID     string         `json:"id"`
+		Temp   float64        `json:"temp"`
+		OK     bool           `json:"ok"`
+		Code   [3]byte        `json:"code"`
+		Inner  Inner          `json:"inner"`
+		Count  *int           `json:"count"`
+		Labels map[string]int `json:"labels"`
+		Small  int32          `json:"small"`
+	}
+	type D struct {
+		Base
+		ID     ID             `json:"id"`
+		Temp   Celsius        `json:"temp"`
+		OK     bool           `json:"ok"`
+		Code   [3]byte        `json:"code"`
+		Inner  Inner          `json:"inner"`
+		Count  *int           `json:"count"`
+		Labels map[string]int `json:"labels"`
+		Small  int64          `json:"small"`
+	}
+	n := 7
+	src := S{Base: &Base{Created: 9}, ID: "a", Temp: 21.5, OK: true, Code: [3]byte{'x', 'y', 'z'},
+		Inner: Inner{Tags: []string{"t"}}, Count: &n, Labels: map[string]int{"k": 1}, Small: -3}
+
+	p, err := BuildPlan[S, D](Options{UnsafeCopy: true})
+	require.NoError(t, err, "BuildPlan failed")
+	copied := map[string]bool{}
+	for _, s := range p.root.steps {
+		copied[s.name] = s.copy != nil
+	}
+	assert.Equal(t, map[string]bool{"Created": false, "ID": true, "Temp": true, "OK": true, "Code": true,
+		"Inner": true, "Count": false, "Labels": true, "Small": false}, copied,
+		"embedded pointers, pointers and widening keep their converters")
+
+	var got, want D
+	require.NoError(t, p.Convert(&got, &src), "Convert failed")
+	require.NoError(t, Convert(&src, &want), "Convert failed")
+	assert.Equal(t, want, got)
+	assert.NotSame(t, src.Count, got.Count, "pointers are still copied into fresh values")
+
+	pm, err := BuildPlan[struct{ M map[string]S }, struct{ M map[string]D }](Options{UnsafeCopy: true})
+	require.NoError(t, err, "BuildPlan failed")
+	var dm struct{ M map[string]D }
+	require.NoError(t, pm.Convert(&dm, &struct{ M map[string]S }{M: map[string]S{"a": src}}), "map values are not addressable")
+	assert.Equal(t, want, dm.M["a"])
+
+	// A nil embedded source pointer converts like a nil source.
+	for _, unsafeCopy := range []bool{false, true} {
+		p, err := BuildPlan[S, D](Options{UnsafeCopy: unsafeCopy})
+		require.NoError(t, err, "BuildPlan failed")
+		got = D{Base: Base{Created: 1}}
+		require.NoError(t, p.Convert(&got, &S{ID: "b"}), "Convert failed")
+		assert.Equal(t, D{ID: "b"}, got, "UnsafeCopy=%v", unsafeCopy)
+
+		back, err := BuildPlan[D, S](Options{UnsafeCopy: unsafeCopy})
+		require.NoError(t, err, "BuildPlan failed")
+		var s S
+		require.NoError(t, back.Convert(&s, &D{ID: "c"}), "Convert failed")
+		assert.Equal(t, &Base{}, s.Base, "nil embedded destination pointers are allocated")
+	}
+	type R struct {
+		*Base
+		ID string `json:"id"`
+	}
+	err = Convert(&S{}, &R{}, Required("Created"))
+	assert.ErrorIs(t, err, ErrRequired)
+	var r R
+	require.NoError(t, Convert(&S{}, &r, Default("Created", int64(5))))
+	assert.Equal(t, int64(5), r.Created, "defaults fill fields behind nil embedded pointers")
+}
+
+func BenchmarkUnsafeCopy(b *testing.B) {
+	type Row struct {
+		ID      int64   `json:"id"`
+		Name    string  `json:"name"`
+		Email   string  `json:"email"`
+		Age     int     `json:"age"`
+		Score   float64 `json:"score"`
+		Active  bool    `json:"active"`
+		Created int64   `json:"created"`
+		Items   []ItemA `json:"items"`
+	}
+	type View struct {
+		ID      int64   `json:"id"`
+		Name    string  `json:"name"`
+		Email   string  `json:"email"`
+		Age     int     `json:"age"`
+		Score   float64 `json:"score"`
+		Active  bool    `json:"active"`
+		Created int64   `json:"created"`
+		Items   []ItemB `json:"items"`
+	}
+	row := Row{ID: 1, Name: "n", Email: "e", Age: 30, Score: 1.5, Active: true, Created: 99, Items: []ItemA{{Value: 5}, {Value: 6}}}
+	for _, unsafeCopy := range []bool{false, true} {
+		b.Run(fmt.Sprintf("UnsafeCopy=%v", unsafeCopy), func(b *testing.B) {
+			p, err := BuildPlan[Row, View](Options{UnsafeCopy: unsafeCopy})
+			if err != nil {
+				b.Fatal(err)
+			}
+			var v View
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := p.Convert(&v, &row); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	cconv := func(src *CustomTypeA, dst *CustomTypeB) error {
+		v, err := strconv.Atoi(string(*src))
+		*dst = CustomTypeB(v)
+		return err
+	}
+	a := A{ID: "bench", Name: "world", Meta: map[string]int{"x": 42}, Items: []ItemA{{Value: 5}, {Value: 6}},
+		Untagged: "untagged-value", Custom: CustomTypeA("1234")}
+	for _, unsafeCopy := range []bool{false, true} {
+		b.Run(fmt.Sprintf("Compare/UnsafeCopy=%v", unsafeCopy), func(b *testing.B) {
+			p, err := BuildPlan[A, B](Options{UnsafeCopy: unsafeCopy}, cconv)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var out B
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := p.Convert(&out, &a); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDirectConversion(t *testing.T) {
+	type Audit struct {
+		Created int64 `json:"created"`
+	}
+	type Address struct {
+		City string `json:"city"`
+	}
+	type Row struct {
+		Audit
+		ID      int64    `json:"id" db:"id"`
+		Tags    []string `json:"tags" db:"tags"`
+		Address Address  `json:"address" db:"-"`
+	}
+	type User struct {
+		Audit
+		ID      int64    `json:"id"`
+		Tags    []string `json:"tags"`
+		Address Address  `json:"address"`
+	}
+	p, err := BuildPlan[Row, User](Options{})
+	require.NoError(t, err, "BuildPlan failed")
+	assert.True(t, p.root.direct, "tags other than the field mapping do not matter")
+	assert.True(t, p.Describe().Direct)
+
+	row := Row{Audit: Audit{Created: 1}, ID: 2, Tags: []string{"a"}, Address: Address{City: "Oslo"}}
+	var u User
+	require.NoError(t, p.Convert(&u, &row), "Convert failed")
+	assert.Equal(t, User{Audit: Audit{Created: 1}, ID: 2, Tags: []string{"a"}, Address: Address{City: "Oslo"}}, u)
+	assert.Zero(t, testing.AllocsPerRun(100, func() { _ = p.Convert(&u, &row) }))
+
+	type Partial struct {
+		Audit
+		ID      int64    `json:"id"`
+		Tags    []string `json:"-"`
+		Address Address  `json:"address"`
+	}
+	ps, err := BuildPlan[Row, Partial](Options{})
+	require.NoError(t, err, "BuildPlan failed")
+	assert.False(t, ps.root.direct, "unmapped fields rule out a direct conversion")
+	var part Partial
+	require.NoError(t, ps.Convert(&part, &row), "Convert failed")
+	assert.Nil(t, part.Tags)
+
+	type PtrRow struct {
+		ID *int64 `json:"id"`
+	}
+	type PtrUser struct {
+		ID *int64 `json:"id"`
+	}
+	pp, err := BuildPlan[PtrRow, PtrUser](Options{})
+	require.NoError(t, err, "BuildPlan failed")
+	assert.False(t, pp.root.direct)
+	id := int64(3)
+	var pu PtrUser
+	require.NoError(t, pp.Convert(&pu, &PtrRow{ID: &id}), "Convert failed")
+	assert.NotSame(t, &id, pu.ID, "pointer fields still get fresh values")
+
+	type Outer struct {
+		Home  Address            `json:"home"`
+		Homes map[string]Address `json:"homes"`
+	}
+	type Home struct {
+		City string `json:"city"`
+	}
+	type OuterView struct {
+		Home  Home            `json:"home"`
+		Homes map[string]Home `json:"homes"`
+		Extra string          `json:"extra"`
+	}
+	po, err := BuildPlan[Outer, OuterView](Options{})
+	require.NoError(t, err, "BuildPlan failed")
+	d := po.Describe()
+	assert.False(t, d.Direct)
+	assert.True(t, d.Fields[0].Direct, "nested pairs convert directly")
+	var ov OuterView
+	require.NoError(t, po.Convert(&ov, &Outer{Home: Address{City: "a"}, Homes: map[string]Address{"b": {City: "b"}}}))
+	assert.Equal(t, OuterView{Home: Home{City: "a"}, Homes: map[string]Home{"b": {City: "b"}}}, ov, "map values are converted without their address")
+}
+
 func TestUntaggedFieldNameMatching(t *testing.T) {
 	type S struct {
 		Name  string